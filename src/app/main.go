@@ -5,9 +5,53 @@ import (
 	"autosimulator/src/graphics"
 	"autosimulator/src/machine"
 	"autosimulator/src/machine/afdMachine"
+	"autosimulator/src/theme"
+	"flag"
+	"fmt"
+	"os"
 )
 
 func main() {
+	configPath := flag.String("config", "", "arquivo JSON de configuração (opcional)")
+	machines := flag.String("machines", "", "diretorio das maquinas (padrão: "+graphics.EXAMPLES_PATH+")")
+	inputs := flag.String("inputs", "", "diretorio das entradas (padrão: "+graphics.INPUT_PATH+")")
+	exports := flag.String("exports", "", "diretorio das animações exportadas (padrão: "+graphics.EXPORTS_PATH+")")
+	font := flag.String("font", "", "arquivo .ttf ou nome de uma fonte embutida (padrão: "+graphics.DEFAULT_FONT+")")
+	themeName := flag.String("theme", "", "tema embutido (dark, light, colorblind) ou arquivo JSON com as cores (padrão: "+theme.THEME_DARK+")")
+	width := flag.Int("width", 0, "largura inicial da janela")
+	height := flag.Int("height", 0, "altura inicial da janela")
+	machinePath := flag.String("machine", "", "maquina aberta ao iniciar (opcional)")
+	inputPath := flag.String("input", "", "arquivo CSV com a entrada aberta ao iniciar (opcional)")
+	flag.Parse()
+
+	// As flags têm preferencia sobre o arquivo de configuração
+	cfg := graphics.DefaultConfig()
+	if *configPath != "" {
+		var err error
+		cfg, err = graphics.LoadConfig(*configPath)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	cfg = cfg.Merge(graphics.Config{
+		MachinesDir: *machines,
+		InputsDir:   *inputs,
+		ExportsDir:  *exports,
+		Font:        *font,
+		Theme:       *themeName,
+		Width:       int32(*width),
+		Height:      int32(*height),
+		Machine:     *machinePath,
+		Input:       *inputPath,
+	})
+
+	if cfg.Input != "" && cfg.Machine == "" {
+		fmt.Println("a entrada inicial precisa de uma maquina (-machine)")
+		os.Exit(2)
+	}
+
 	m := &afdMachine.Machine{
 		BaseMachine: machine.BaseMachine{
 			Type:         "simple_machine",
@@ -18,7 +62,16 @@ func main() {
 		},
 	}
 
-	window := graphics.NewSDLWindow()
+	window := graphics.NewSDLWindow(cfg)
 	environment := graphics.PopulateEnvironment(window, m)
+	if cfg.Machine != "" {
+		err := environment.OpenFiles(cfg.Machine, cfg.Input)
+		if err != nil {
+			fmt.Println(err)
+			environment.Destroy()
+			os.Exit(1)
+		}
+	}
+
 	graphics.Mainloop(environment)
 }
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"autosimulator/src/machine"
+	"autosimulator/src/reader"
+	"autosimulator/src/render"
+	"autosimulator/src/theme"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Modo headless: desenha o diagrama das maquinas em SVG, sem abrir janela.
+// Útil no CI para gerar as imagens das maquinas em machines/.
+func main() {
+	machinePath := flag.String("machine", "", "arquivo JSON da maquina")
+	dir := flag.String("dir", "", "renderiza todas as maquinas do diretorio")
+	inputPath := flag.String("input", "", "arquivo CSV com a entrada (padrão: entrada default da maquina)")
+	out := flag.String("out", "", "arquivo (ou diretorio, com -dir) de saida")
+	step := flag.Int("step", -1, "passo da computação a ser desenhado (-1 para o ultimo)")
+	themeName := flag.String("theme", "", "tema embutido (dark, light, colorblind) ou arquivo JSON com as cores (padrão: "+theme.THEME_DARK+")")
+	flag.Parse()
+
+	t, err := theme.Load(*themeName)
+	if err == nil {
+		err = render.SetTheme(t)
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	switch {
+	case *dir != "":
+		err = renderDir(*dir, *inputPath, *out, *step)
+	case *machinePath != "":
+		output := *out
+		if output == "" {
+			output = svgName(*machinePath)
+		}
+
+		err = renderFile(*machinePath, *inputPath, output, *step)
+	default:
+		fmt.Println("uso: render (-machine <maquina.json> | -dir <diretorio>) [-input <entrada.csv>] [-out <saida>] [-step <n>]")
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func renderDir(dir, inputPath, out string, step int) error {
+	files, err := reader.GetJsonList(dir)
+	if err != nil {
+		return err
+	}
+
+	if out == "" {
+		out = dir
+	}
+
+	err = os.MkdirAll(out, 0o755)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		output := filepath.Join(out, svgName(file))
+		err = renderFile(filepath.Join(dir, file), inputPath, output, step)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func renderFile(machinePath, inputPath, output string, step int) error {
+	m, err := reader.ReadMachine(machinePath)
+	if err != nil {
+		return err
+	}
+
+	input := m.GetInput()
+	if inputPath != "" {
+		input, err = reader.ReadInput(inputPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Uma maquina que não para não pode travar o CI
+	comp, err := machine.ExecuteWithOptions(m, input, machine.ExecOptions{
+		Mode:     machine.LENIENT_ALFABET,
+		MaxSteps: machine.MAX_STEPS,
+		Quiet:    true,
+	})
+	if err != nil {
+		return err
+	}
+
+	input.Reset()
+	if comp.Limited {
+		fmt.Printf("%s: a computação parou no limite de %d passos\n", machinePath, machine.MAX_STEPS)
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	err = render.SVG(f, m, comp, step)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s -> %s\n", machinePath, output)
+	return nil
+}
+
+func svgName(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base)) + ".svg"
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"autosimulator/src/machine"
+	"autosimulator/src/repl"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// Modo terminal: depurador passo a passo que não depende do SDL, podendo
+// ser usado via SSH.
+func main() {
+	machinePath := flag.String("machine", "", "arquivo JSON da maquina")
+	inputPath := flag.String("input", "", "arquivo CSV com a entrada (opcional)")
+	strict := flag.Bool("strict", false, "recusa entradas com simbolos fora do alfabeto")
+	flag.Parse()
+
+	if *machinePath == "" {
+		fmt.Println("uso: repl -machine <maquina.json> [-input <entrada.csv>] [-strict]")
+		os.Exit(2)
+	}
+
+	mode := machine.LENIENT_ALFABET
+	if *strict {
+		mode = machine.STRICT_ALFABET
+	}
+
+	debugger, err := repl.New(*machinePath, *inputPath, mode, os.Stdin, os.Stdout)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	err = debugger.Run()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"autosimulator/src/server"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// Modo servidor: expõe o simulador via HTTP/JSON, sem depender do SDL.
+func main() {
+	addr := flag.String("addr", "localhost:8080", "endereço do servidor")
+	machines := flag.String("machines", "machines", "diretorio com as maquinas pré-carregadas")
+	crossOrigin := flag.Bool("cross-origin", false, "aceita o /ws vindo de paginas de outras origens")
+	flag.Parse()
+
+	s := server.New()
+	s.CrossOrigin = *crossOrigin
+	if *machines != "" {
+		err := s.LoadMachines(*machines)
+		if err != nil {
+			fmt.Println(err)
+		}
+	}
+
+	fmt.Printf("Servidor rodando em http://%s\n", *addr)
+	err := http.ListenAndServe(*addr, s.Handler())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
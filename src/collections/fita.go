@@ -41,6 +41,24 @@ func (f *Fita) Reset() {
 	f.current = f.first
 }
 
+// Quantidade de simbolos já lidos
+func (f *Fita) Position() int {
+	position := 0
+	for node := f.first; node != nil && node != f.current; node = node.next {
+		position++
+	}
+
+	return position
+}
+
+// Volta a cabeça para o inicio e lê position simbolos
+func (f *Fita) Seek(position int) {
+	f.Reset()
+	for i := 0; i < position && f.current != nil; i++ {
+		f.current = f.current.next
+	}
+}
+
 func (f *Fita) Write(item string) {
 	newNode := &node{
 		value: item,
@@ -126,6 +144,22 @@ func (f *Fita) Stringfy() string {
 	return s
 }
 
+// Igual ao Stringfy, mas marca com colchetes a celula na posição da cabeça
+func (f *Fita) StringfyHead(head int) string {
+	var s string
+	current := f.first
+	for i := 0; current != nil; i++ {
+		if i == head {
+			s += fmt.Sprintf("[%s] ", current.value)
+		} else {
+			s += fmt.Sprintf("%s ", current.value)
+		}
+		current = current.next
+	}
+
+	return s
+}
+
 func (f *Fita) UnmarshalJSON(data []byte) error {
 	arr := []string{}
 	err := json.Unmarshal(data, &arr)
@@ -17,11 +17,27 @@ type (
 	}
 )
 
+// Simbolo no fundo de toda pilha
+const (
+	FUNDO_PILHA = "?"
+)
+
 func NewStack() *Stack {
-	firstN := &node{"?", nil}
+	firstN := &node{FUNDO_PILHA, nil}
 	return &Stack{firstN, 1}
 }
 
+// Cria uma pilha a partir dos valores da base para o topo. O primeiro valor
+// deve ser o fundo da pilha ("?"), como é guardado no historico.
+func StackFromArray(values []string) *Stack {
+	s := &Stack{nil, 0}
+	for _, v := range values {
+		s.Push(v)
+	}
+
+	return s
+}
+
 func (s *Stack) Length() int {
 	return s.len
 }
@@ -0,0 +1,218 @@
+package graphics
+
+import (
+	"autosimulator/src/collections"
+	"autosimulator/src/machine"
+	"autosimulator/src/reader"
+	"fmt"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Painel de execução em lote: todas as linhas de um CSV rodam na maquina
+// carregada e o painel lista cada entrada com o resultado e a quantidade de
+// passos. Clicar em uma linha carrega a entrada e reproduz a computação na
+// visão normal. A tecla b mostra e esconde o painel.
+
+type batchPanel struct {
+	active bool
+	path   string
+	rows   []batchRow
+
+	// Primeira linha visivel e linha reproduzida por ultimo (-1 se nenhuma)
+	offset   int
+	selected int
+
+	// Area das linhas no ultimo desenho, para o clique e a roda do mouse
+	rect sdl.Rect
+}
+
+type batchRow struct {
+	input       *collections.Fita
+	computation *machine.Computation
+
+	// Erro do alfabeto no modo estrito. A entrada não roda.
+	err error
+}
+
+// Lê o CSV e roda todas as linhas
+func (batch *batchPanel) load(env *environment, path string) error {
+	inputs, err := reader.ReadInputs(path)
+	if err != nil {
+		return &fileError{path: path, err: err}
+	}
+
+	batch.path = path
+	batch.rows = make([]batchRow, len(inputs))
+	for i, input := range inputs {
+		batch.rows[i].input = input
+	}
+
+	batch.offset = 0
+	batch.selected = -1
+	batch.active = true
+	batch.run(env)
+	return nil
+}
+
+// Roda as linhas na maquina carregada. Chamada de novo quando a maquina
+// muda. Cada linha para em machine.MAX_STEPS passos, para que uma entrada
+// em loop não trave a janela.
+func (batch *batchPanel) run(env *environment) {
+	opts := machine.ExecOptions{Mode: env.alfabetMode, MaxSteps: machine.MAX_STEPS, Quiet: true}
+	for i := range batch.rows {
+		row := &batch.rows[i]
+		row.input.Reset()
+		row.computation, row.err = machine.ExecuteWithOptions(env.machine, row.input, opts)
+		row.input.Reset()
+	}
+
+	// A execução troca a entrada da maquina
+	env.machine.Init(env.input)
+	env.input.Reset()
+}
+
+func (batch *batchPanel) toggle() {
+	batch.active = !batch.active && batch.rows != nil
+}
+
+// Reproduz a linha clicada. Retorna false se o clique não foi em uma linha.
+func (batch *batchPanel) handleClick(p sdl.Point, env *environment) (bool, error) {
+	if !p.InRect(&batch.rect) {
+		return false, nil
+	}
+
+	index := batch.offset + int((p.Y-batch.rect.Y)/DIMENSAO_ESTRUTURAS)
+	if index >= len(batch.rows) {
+		return false, nil
+	}
+
+	row := batch.rows[index]
+	if row.err != nil {
+		return true, row.err
+	}
+
+	// A visão normal roda a computação inteira, sem o limite do lote
+	if row.computation.Limited {
+		return true, fmt.Errorf("a entrada %d passou do limite de %d passos", index+1, machine.MAX_STEPS)
+	}
+
+	err := env.setInput(row.input)
+	if err != nil {
+		return true, err
+	}
+
+	env.inputPath = ""
+	env.watchFiles()
+	ui.init(env, false)
+
+	batch.selected = index
+	batch.active = false
+	env.running = len(ui.bufferComputation.History) > 1
+	fpsTimer = sdl.GetTicks64()
+	return true, nil
+}
+
+func (batch *batchPanel) scroll(amount int) {
+	batch.offset += amount
+	if batch.offset > len(batch.rows)-batch.visibleRows() {
+		batch.offset = len(batch.rows) - batch.visibleRows()
+	}
+
+	if batch.offset < 0 {
+		batch.offset = 0
+	}
+}
+
+func (batch *batchPanel) visibleRows() int {
+	return int(batch.rect.H / DIMENSAO_ESTRUTURAS)
+}
+
+func (batch *batchPanel) draw(window *_SDLWindow) error {
+	bounds := diagramBounds(window)
+	rect := sdl.Rect{X: bounds.X, Y: bounds.Y, W: bounds.W, H: bounds.H}
+	err := drawRect(window.renderer, 2, rect, COLOR_DEFAULT, COLOR_BACKGROUD)
+	if err != nil {
+		return err
+	}
+
+	accepted := 0
+	for _, row := range batch.rows {
+		if row.err == nil && row.computation.Accepted() {
+			accepted++
+		}
+	}
+
+	header := fmt.Sprintf("%s: %d/%d aceitas", machineName(batch.path), accepted, len(batch.rows))
+	maxLen := int(bounds.W/(DIMENSAO_ESTRUTURAS/2)) - 2
+	err = drawText(window, []string{header}, 0, rect.X+PADX*2, rect.Y+DIMENSAO_ESTRUTURAS/2+PADY, maxLen, TEXT_DOWN_LEFT)
+	if err != nil {
+		return err
+	}
+
+	// Linhas abaixo do titulo. A coluna do resultado fica à direita.
+	batch.rect = sdl.Rect{
+		X: rect.X + PADX*2,
+		Y: rect.Y + DIMENSAO_ESTRUTURAS + PADY*2,
+		W: rect.W - PADX*4,
+		H: rect.H - DIMENSAO_ESTRUTURAS - PADY*4,
+	}
+
+	batch.scroll(0)
+	var resultWidth int32 = DIMENSAO_ESTRUTURAS * 5
+	inputLen := int((batch.rect.W-resultWidth)/(DIMENSAO_ESTRUTURAS/2)) - 1
+	for i := 0; i < batch.visibleRows() && batch.offset+i < len(batch.rows); i++ {
+		index := batch.offset + i
+		row := batch.rows[index]
+		y := batch.rect.Y + DIMENSAO_ESTRUTURAS*int32(i)
+		cell := sdl.Rect{X: batch.rect.X, Y: y, W: batch.rect.W, H: DIMENSAO_ESTRUTURAS}
+
+		if index == batch.selected {
+			err = drawRect(window.renderer, 1, cell, COLOR_DEFAULT, COLOR_BACKGROUD)
+			if err != nil {
+				return err
+			}
+		}
+
+		// A fita sem o simbolo de fim
+		symbols := row.input.ToArray()
+		text := fmt.Sprintf("%d: %s", index+1, strings.Join(symbols[:len(symbols)-1], " "))
+		err = drawText(window, []string{text}, 0, cell.X+PADX, y+DIMENSAO_ESTRUTURAS/2, inputLen, TEXT_DOWN_LEFT)
+		if err != nil {
+			return err
+		}
+
+		result, color := batchResult(row)
+		center := sdl.Point{X: cell.X + cell.W - resultWidth/2, Y: y + DIMENSAO_ESTRUTURAS/2}
+		err = drawTextColor(window, result, center, color)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Indica que há mais linhas abaixo
+	if batch.offset+batch.visibleRows() < len(batch.rows) {
+		return drawTextColor(window, "...", sdl.Point{X: rect.X + rect.W/2, Y: rect.Y + rect.H - PADY*2}, COLOR_DEFAULT)
+	}
+
+	return nil
+}
+
+// Resultado e passos de uma linha, na cor do estado final
+func batchResult(row batchRow) (string, sdl.Color) {
+	if row.err != nil {
+		return "alfabeto", COLOR_WARNING
+	}
+
+	steps := len(row.computation.History) - 1
+	if row.computation.Limited {
+		return fmt.Sprintf("%s %d", machine.LIMITED, steps), COLOR_INITIAL
+	}
+
+	if row.computation.Accepted() {
+		return fmt.Sprintf("%s %d", machine.ACCEPTED, steps), COLOR_ACCEPTED
+	}
+
+	return fmt.Sprintf("%s %d", machine.REJECTED, steps), COLOR_REJECTED
+}
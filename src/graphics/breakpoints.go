@@ -0,0 +1,137 @@
+package graphics
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/veandco/go-sdl2/gfx"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Breakpoints da animação automatica (espaço): ela pausa ao entrar em um
+// estado marcado ou ao fazer uma transição marcada. O botão direito marca e
+// desmarca o estado ou o rotulo da transição sob o mouse; a configuração
+// traz os breakpoints iniciais, com o mesmo formato do REPL.
+
+const (
+	BREAKPOINT_RADIUS = 5
+)
+
+type breakpoints struct {
+	states map[string]bool
+
+	// Transições sem espaços, como "(a,q1)". Valem para qualquer estado de
+	// origem, como no REPL.
+	transitions map[string]bool
+
+	// Area de cada rotulo de transição no ultimo desenho, para o clique
+	labels []labelArea
+}
+
+type labelArea struct {
+	rect       sdl.Rect
+	transition string
+}
+
+// Breakpoints da configuração. Entradas entre parenteses são transições e
+// as demais, estados.
+func newBreakpoints(entries []string) *breakpoints {
+	bp := &breakpoints{
+		states:      make(map[string]bool),
+		transitions: make(map[string]bool),
+	}
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if strings.HasPrefix(entry, "(") {
+			bp.transitions[normalizeTransition(entry)] = true
+		} else if entry != "" {
+			bp.states[entry] = true
+		}
+	}
+
+	return bp
+}
+
+func (bp *breakpoints) toggleState(state string) {
+	bp.states[state] = !bp.states[state]
+}
+
+func (bp *breakpoints) toggleTransition(transition string) {
+	key := normalizeTransition(transition)
+	bp.transitions[key] = !bp.transitions[key]
+}
+
+func (bp *breakpoints) hasTransition(transition string) bool {
+	return bp.transitions[normalizeTransition(transition)]
+}
+
+// Motivo da pausa no passo com os detalhes details. false se o passo não
+// tem breakpoint.
+func (bp *breakpoints) hit(details map[string]string) (string, bool) {
+	if details["TRANSITION"] == "" {
+		return "", false
+	}
+
+	if bp.states[details["NEXT_STATE"]] {
+		return fmt.Sprintf("breakpoint: estado %s", details["NEXT_STATE"]), true
+	}
+
+	if bp.hasTransition(details["TRANSITION"]) {
+		return fmt.Sprintf("breakpoint: transição %s", details["TRANSITION"]), true
+	}
+
+	return "", false
+}
+
+// Marca o rotulo ou o estado sob o clique. screen é a posição na tela e
+// world no mundo. Retorna false se o clique não foi em nenhum dos dois.
+func (bp *breakpoints) handleClick(screen, world sdl.Point) bool {
+	for _, label := range bp.labels {
+		if screen.InRect(&label.rect) {
+			bp.toggleTransition(label.transition)
+			return true
+		}
+	}
+
+	state := stateAt(&world)
+	if state == nil {
+		return false
+	}
+
+	bp.toggleState(state.state)
+	return true
+}
+
+// Ponto no canto do estado marcado
+func (bp *breakpoints) drawState(renderer *sdl.Renderer, s *graphicalState) error {
+	if !bp.states[s.state] {
+		return nil
+	}
+
+	if ok := gfx.FilledCircleColor(renderer, s.X+s.W-BREAKPOINT_RADIUS, s.Y+BREAKPOINT_RADIUS, BREAKPOINT_RADIUS, COLOR_ERROR); !ok {
+		return fmt.Errorf("não foi possivel desenhar o breakpoint do estado %s", s.state)
+	}
+
+	return nil
+}
+
+// Guarda a area do rotulo e desenha o ponto à esquerda dele se a transição
+// está marcada
+func (bp *breakpoints) drawLabel(renderer *sdl.Renderer, rect sdl.Rect, transition string) error {
+	bp.labels = append(bp.labels, labelArea{rect: rect, transition: transition})
+	if !bp.hasTransition(transition) {
+		return nil
+	}
+
+	radius := rect.H / 4
+	if ok := gfx.FilledCircleColor(renderer, rect.X-radius*2, rect.Y+rect.H/2, radius, COLOR_ERROR); !ok {
+		return fmt.Errorf("não foi possivel desenhar o breakpoint da transição %s", transition)
+	}
+
+	return nil
+}
+
+func normalizeTransition(transition string) string {
+	return strings.ReplaceAll(transition, " ", "")
+}
@@ -0,0 +1,136 @@
+package graphics
+
+import (
+	"math"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Camera do diagrama. Os estados guardam a posição no "mundo" (a mesma
+// salva no layout da maquina) e a camera converte para a tela:
+// tela = (mundo - origem) * zoom. Os paineis da fita, pilhas e historico não
+// passam pela camera.
+
+const (
+	ZOOM_MIN  = 0.5
+	ZOOM_MAX  = 4.0
+	ZOOM_STEP = 1.1
+)
+
+type camera struct {
+	// Ponto do mundo no canto superior esquerdo da janela
+	x, y float64
+	zoom float64
+
+	// Arraste com o botão do meio
+	panning  bool
+	panStart sdl.Point
+	panX     float64
+	panY     float64
+}
+
+func newCamera() *camera {
+	return &camera{zoom: 1}
+}
+
+func (c *camera) reset() {
+	c.x, c.y, c.zoom = 0, 0, 1
+	c.panning = false
+}
+
+func (c *camera) toScreen(rect *sdl.Rect) *sdl.Rect {
+	return &sdl.Rect{
+		X: int32(math.Round((float64(rect.X) - c.x) * c.zoom)),
+		Y: int32(math.Round((float64(rect.Y) - c.y) * c.zoom)),
+		W: int32(math.Round(float64(rect.W) * c.zoom)),
+		H: int32(math.Round(float64(rect.H) * c.zoom)),
+	}
+}
+
+func (c *camera) toWorld(p sdl.Point) sdl.Point {
+	return sdl.Point{
+		X: int32(math.Round(float64(p.X)/c.zoom + c.x)),
+		Y: int32(math.Round(float64(p.Y)/c.zoom + c.y)),
+	}
+}
+
+// Aplica o zoom mantendo fixo o ponto do mundo que esta sob p (na tela)
+func (c *camera) zoomAt(p sdl.Point, factor float64) {
+	zoom := c.zoom * factor
+	if zoom < ZOOM_MIN {
+		zoom = ZOOM_MIN
+	}
+
+	if zoom > ZOOM_MAX {
+		zoom = ZOOM_MAX
+	}
+
+	worldX := float64(p.X)/c.zoom + c.x
+	worldY := float64(p.Y)/c.zoom + c.y
+	c.zoom = zoom
+	c.x = worldX - float64(p.X)/zoom
+	c.y = worldY - float64(p.Y)/zoom
+}
+
+func (c *camera) startPan(p sdl.Point) {
+	c.panning = true
+	c.panStart = p
+	c.panX, c.panY = c.x, c.y
+}
+
+func (c *camera) pan(p sdl.Point) {
+	if !c.panning {
+		return
+	}
+
+	c.x = c.panX - float64(p.X-c.panStart.X)/c.zoom
+	c.y = c.panY - float64(p.Y-c.panStart.Y)/c.zoom
+}
+
+func (c *camera) stopPan() {
+	c.panning = false
+}
+
+// Enquadra todos os estados na area do diagrama da janela
+func (c *camera) fit(states map[string]*graphicalState, window *_SDLWindow) {
+	if len(states) == 0 {
+		c.reset()
+		return
+	}
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, state := range states {
+		minX = math.Min(minX, float64(state.X))
+		minY = math.Min(minY, float64(state.Y))
+		maxX = math.Max(maxX, float64(state.X+state.W))
+		maxY = math.Max(maxY, float64(state.Y+state.H))
+	}
+
+	// Margem para os laços e rotulos em volta dos estados
+	minX -= WIDTH_REC
+	maxX += WIDTH_REC
+	minY -= HEIGTH_REC * 2
+	maxY += HEIGTH_REC / 2
+
+	bounds := diagramBounds(window)
+	zoom := math.Min(float64(bounds.W)/(maxX-minX), float64(bounds.H)/(maxY-minY))
+	zoom = math.Max(ZOOM_MIN, math.Min(ZOOM_MAX, zoom))
+
+	// Centraliza o diagrama na area
+	c.zoom = zoom
+	c.x = (minX+maxX)/2 - (float64(bounds.X)+float64(bounds.W)/2)/zoom
+	c.y = (minY+maxY)/2 - (float64(bounds.Y)+float64(bounds.H)/2)/zoom
+}
+
+// Copia dos estados com as posições na tela, para desenhar
+func (c *camera) view(states map[string]*graphicalState) map[string]*graphicalState {
+	result := make(map[string]*graphicalState)
+	for name, state := range states {
+		copy := *state
+		copy.Rect = c.toScreen(state.Rect)
+		result[name] = &copy
+	}
+
+	return result
+}
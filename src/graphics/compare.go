@@ -0,0 +1,237 @@
+package graphics
+
+import (
+	"autosimulator/src/layout"
+	"autosimulator/src/machine"
+	"autosimulator/src/reader"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/veandco/go-sdl2/gfx"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Comparação lado a lado: uma segunda maquina (por exemplo a de referencia
+// do professor) roda sobre a mesma entrada da maquina carregada. O diagrama
+// é dividido ao meio, com a maquina carregada à esquerda, e as duas avançam
+// juntas, passo a passo. O primeiro passo em que as pilhas ou o resultado
+// diferem é destacado e a animação para nele.
+
+const (
+	// Altura dos cabeçalhos acima de cada metade
+	COMPARE_HEADER = DIMENSAO_ESTRUTURAS * 3
+)
+
+type comparison struct {
+	machine     machine.Machine
+	path        string
+	states      map[string]*graphicalState
+	computation *machine.Computation
+
+	// nil se as duas computações são iguais
+	divergence *machine.Divergence
+
+	// Transição feita pela segunda maquina no passo atual
+	fired *firedTransition
+
+	// A animação já parou na divergencia
+	stopped bool
+}
+
+// Abre a segunda maquina e divide o diagrama
+func (ui *uiComponents) startComparison(env *environment, path string) error {
+	m, err := reader.ReadMachine(path)
+	if err != nil {
+		return &fileError{path: path, err: err}
+	}
+
+	// A entrada atual precisa respeitar o alfabeto das duas maquinas
+	_, err = machine.CheckInput(m, env.input, env.alfabetMode)
+	if err != nil {
+		return &fileError{path: path, err: err}
+	}
+
+	ui.compare = &comparison{machine: m, path: path}
+	ui.init(env, true)
+	return nil
+}
+
+func (ui *uiComponents) stopComparison(env *environment) {
+	ui.compare = nil
+	ui.init(env, true)
+}
+
+// Roda a segunda maquina na entrada atual, até machine.MAX_STEPS passos.
+// Deve ser chamada depois que a computação da maquina carregada muda.
+func (c *comparison) run(env *environment) {
+	opts := machine.ExecOptions{Mode: env.alfabetMode, MaxSteps: machine.MAX_STEPS, Quiet: true}
+	computation, err := machine.ExecuteWithOptions(c.machine, env.input, opts)
+	if err != nil {
+		// A entrada nova já foi aceita pela maquina carregada, então a
+		// segunda roda no modo leniente e o erro fica no canto da tela
+		ui.setStatus(fmt.Sprintf("%s: %s", machineName(c.path), err))
+		opts.Mode = machine.LENIENT_ALFABET
+		computation, _ = machine.ExecuteWithOptions(c.machine, env.input, opts)
+	}
+
+	c.computation = computation
+	env.input.Reset()
+	c.diff()
+}
+
+func (c *comparison) diff() {
+	c.divergence = machine.Compare(&ui.bufferComputation, c.computation)
+	c.stopped = false
+}
+
+func (c *comparison) layout(window *_SDLWindow) {
+	_, right := splitBounds(window)
+	c.states = statesOf(c.machine, right, false)
+}
+
+func (c *comparison) update(env *environment) {
+	for _, state := range c.states {
+		state.Color = COLOR_DEFAULT
+		state.result = ""
+	}
+
+	index := stepOf(c.computation, ui.indexComputation)
+	c.fired = colorRecord(c.states, c.computation.History[index])
+
+	// A animação para uma vez no passo da divergencia
+	if env.running && c.divergence != nil && !c.stopped && ui.indexComputation >= c.divergence.Step {
+		env.running = false
+		c.stopped = true
+	}
+}
+
+func (c *comparison) diverged() bool {
+	return c.divergence != nil && ui.indexComputation >= c.divergence.Step
+}
+
+// Desenha a segunda maquina com a mesma camera. O destaque das transições
+// usa ui.fired, que é trocado durante o desenho. Os breakpoints são só da
+// maquina carregada.
+func (c *comparison) draw(env *environment) error {
+	fired, breaks := ui.fired, ui.breaks
+	ui.fired, ui.breaks = c.fired, newBreakpoints(nil)
+	defer func() {
+		ui.fired, ui.breaks = fired, breaks
+	}()
+
+	states := ui.camera.view(c.states)
+	for _, state := range states {
+		err := state.Draw(env.w, states)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Cabeçalho de cada metade com o nome da maquina, o passo atual e as
+// pilhas, e a divergencia entre as duas
+func (c *comparison) drawPanel(env *environment) error {
+	window := env.w
+	left, right := splitBounds(window)
+
+	color := COLOR_DEFAULT
+	if c.diverged() {
+		color = COLOR_ERROR
+	}
+
+	// Divisão entre as metades e, na divergencia, a borda das duas
+	top := left.Y - COMPARE_HEADER
+	if ok := gfx.ThickLineColor(window.renderer, right.X, top, right.X, left.Y+left.H, 2, color); !ok {
+		return fmt.Errorf("não foi possivel desenhar a divisão da comparação")
+	}
+
+	if c.diverged() {
+		if ok := gfx.RectangleColor(window.renderer, left.X, top, right.X+right.W, left.Y+left.H, COLOR_ERROR); !ok {
+			return fmt.Errorf("não foi possivel desenhar a borda da comparação")
+		}
+	}
+
+	indexA := stepOf(&ui.bufferComputation, ui.indexComputation)
+	indexB := stepOf(c.computation, ui.indexComputation)
+	halves := []struct {
+		bounds layout.Bounds
+		path   string
+		record machine.ComputationRecord
+	}{
+		{left, env.machinePath, ui.bufferComputation.History[indexA]},
+		{right, c.path, c.computation.History[indexB]},
+	}
+
+	var spaceBetween int32 = DIMENSAO_ESTRUTURAS / 2
+	for _, half := range halves {
+		maxLen := int(half.bounds.W/(DIMENSAO_ESTRUTURAS/2)) - 1
+		text := []string{
+			machineName(half.path),
+			half.record.Stringfy(),
+			"pilhas: " + stacksText(half.record.Stacks()),
+		}
+
+		err := drawText(window, text, spaceBetween, half.bounds.X+PADX, top+DIMENSAO_ESTRUTURAS/2, maxLen, TEXT_DOWN_LEFT)
+		if err != nil {
+			return err
+		}
+	}
+
+	message := "mesmas pilhas e resultado"
+	if c.divergence != nil {
+		message = c.divergence.Stringfy()
+	}
+
+	// Uma computação cortada pelo limite não tem resultado para comparar
+	if c.computation.Limited && (c.divergence == nil || c.divergence.Reason != machine.DIVERGE_STACKS) {
+		message = fmt.Sprintf("%s %s: parou no limite de %d passos", machine.LIMITED, machineName(c.path), machine.MAX_STEPS)
+	}
+
+	bottom := left.Y + left.H - DIMENSAO_ESTRUTURAS/2
+	return drawTextColor(window, message, sdl.Point{X: window.WIDTH / 2, Y: bottom}, color)
+}
+
+// Metades da area do diagrama, abaixo dos cabeçalhos
+func splitBounds(window *_SDLWindow) (layout.Bounds, layout.Bounds) {
+	bounds := diagramBounds(window)
+	half := bounds.W / 2
+	y := bounds.Y + COMPARE_HEADER
+	h := bounds.H - COMPARE_HEADER
+	left := layout.Bounds{X: bounds.X, Y: y, W: half, H: h}
+	right := layout.Bounds{X: bounds.X + half, Y: y, W: bounds.W - half, H: h}
+	return left, right
+}
+
+// Passo da computação mostrado no passo index da comparação. Depois do fim
+// a computação fica no ultimo passo.
+func stepOf(c *machine.Computation, index int) int {
+	if index > len(c.History)-1 {
+		return len(c.History) - 1
+	}
+
+	return index
+}
+
+func machineName(path string) string {
+	if path == "" {
+		return "maquina"
+	}
+
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+func stacksText(stacks [][]string) string {
+	if len(stacks) == 0 {
+		return "-"
+	}
+
+	var parts []string
+	for _, stack := range stacks {
+		parts = append(parts, fmt.Sprintf("[%s]", strings.Join(stack, " ")))
+	}
+
+	return strings.Join(parts, " ")
+}
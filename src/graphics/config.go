@@ -0,0 +1,145 @@
+package graphics
+
+import (
+	"autosimulator/src/theme"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/veandco/go-sdl2/ttf"
+)
+
+// As fontes padrão vão dentro do binario, que assim pode ser executado de
+// qualquer diretorio
+//
+//go:embed assets/*.ttf
+var fonts embed.FS
+
+type (
+	// Opções da interface grafica, lidas de um arquivo JSON e/ou das flags.
+	// Campos vazios mantêm o valor padrão.
+	Config struct {
+		MachinesDir string `json:"machinesDir"`
+		InputsDir   string `json:"inputsDir"`
+
+		// Diretorio das animações exportadas (teclas g e p)
+		ExportsDir string `json:"exportsDir"`
+
+		// Caminho de um arquivo .ttf ou o nome de uma das fontes embutidas
+		Font string `json:"font"`
+
+		Width  int32 `json:"width"`
+		Height int32 `json:"height"`
+
+		// Maquina e entrada (CSV) abertas ao iniciar. Opcionais.
+		Machine string `json:"machine"`
+		Input   string `json:"input"`
+
+		// Nome de um tema embutido (dark, light ou colorblind) ou caminho de
+		// um arquivo JSON com as cores
+		Theme string `json:"theme"`
+
+		// Estados e transições em que a animação pausa, como no REPL:
+		// "q1" ou "(a, q1)"
+		Breakpoints []string `json:"breakpoints"`
+	}
+)
+
+var config = DefaultConfig()
+
+func DefaultConfig() Config {
+	return Config{
+		MachinesDir: EXAMPLES_PATH,
+		InputsDir:   INPUT_PATH,
+		ExportsDir:  EXPORTS_PATH,
+		Font:        DEFAULT_FONT,
+		Theme:       theme.THEME_DARK,
+		Width:       WITDH,
+		Height:      HEIGHT,
+	}
+}
+
+// Lê o arquivo de configuração sobre os valores padrão
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("erro ao tentar ler o arquivo de configuração: %s. err: %s", path, err)
+	}
+
+	var file Config
+	err = json.Unmarshal(content, &file)
+	if err != nil {
+		return cfg, fmt.Errorf("erro ao tentar fazer o unmarshal do arquivo %s. err: %s", path, err)
+	}
+
+	return cfg.Merge(file), nil
+}
+
+// Retorna a configuração com os campos preenchidos de other
+func (cfg Config) Merge(other Config) Config {
+	if other.MachinesDir != "" {
+		cfg.MachinesDir = other.MachinesDir
+	}
+
+	if other.InputsDir != "" {
+		cfg.InputsDir = other.InputsDir
+	}
+
+	if other.ExportsDir != "" {
+		cfg.ExportsDir = other.ExportsDir
+	}
+
+	if other.Font != "" {
+		cfg.Font = other.Font
+	}
+
+	if other.Width > 0 {
+		cfg.Width = other.Width
+	}
+
+	if other.Height > 0 {
+		cfg.Height = other.Height
+	}
+
+	if other.Machine != "" {
+		cfg.Machine = other.Machine
+	}
+
+	if other.Input != "" {
+		cfg.Input = other.Input
+	}
+
+	if other.Theme != "" {
+		cfg.Theme = other.Theme
+	}
+
+	if len(other.Breakpoints) > 0 {
+		cfg.Breakpoints = other.Breakpoints
+	}
+
+	return cfg
+}
+
+// Abre a fonte da configuração. Um arquivo existente tem preferencia sobre
+// uma fonte embutida de mesmo nome.
+func openFont(size int) (*ttf.Font, error) {
+	if _, err := os.Stat(config.Font); err == nil {
+		return ttf.OpenFont(config.Font, size)
+	}
+
+	data, err := fonts.ReadFile("assets/" + filepath.Base(config.Font))
+	if err != nil {
+		return nil, fmt.Errorf("fonte não encontrada: %s", config.Font)
+	}
+
+	rw, err := sdl.RWFromMem(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return ttf.OpenFontRW(rw, 1, size)
+}
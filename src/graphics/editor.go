@@ -0,0 +1,577 @@
+package graphics
+
+import (
+	"autosimulator/src/collections"
+	"autosimulator/src/machine"
+	"autosimulator/src/reader"
+	"autosimulator/src/utils"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/veandco/go-sdl2/gfx"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// O que esta sendo digitado no editor
+const (
+	EDIT_NONE     = iota
+	EDIT_RENAME   = iota
+	EDIT_LABEL    = iota
+	EDIT_FILENAME = iota
+
+	// Confirmação antes de sobrescrever outro arquivo
+	EDIT_OVERWRITE = iota
+)
+
+const (
+	NEW_MACHINE_FILE = "nova_maquina.json"
+)
+
+var editorHelp = []string{
+	"2x clique: novo estado",
+	"botão direito: transição",
+	"F2: renomear  Del: apagar",
+	"i: inicial  f: final  t: tipo",
+	"F5: salvar  e: sair",
+}
+
+type editor struct {
+	active bool
+	def    *reader.MachineDefinition
+
+	// Estado selecionado com o mouse
+	selected string
+
+	// Transição sendo desenhada com o botão direito
+	linkFrom string
+	linkTo   string
+
+	typing  int
+	text    []string
+	message string
+
+	// Arquivo que espera a confirmação para ser sobrescrito
+	pending string
+}
+
+func (ed *editor) start(env *environment) {
+	ed.active = true
+	ed.def = reader.DefinitionOf(env.machine)
+	ed.def.DefaultInput = reader.InputSymbols(env.defaultInput)
+	ed.selected = ""
+	ed.linkFrom = ""
+	ed.typing = EDIT_NONE
+	ed.message = ""
+	env.running = false
+	ed.refreshStates()
+}
+
+// Sai do modo de edição. As alterações não salvas são descartadas e o
+// diagrama volta a ser o da maquina carregada.
+func (ed *editor) stop(env *environment) {
+	ed.active = false
+	ed.def = nil
+	ed.typing = EDIT_NONE
+
+	states := machineStates(env)
+	keepPositions(states, ui.states)
+	ui.states = states
+}
+
+func (ed *editor) handleKey(event *sdl.KeyboardEvent, env *environment) error {
+	if ed.typing != EDIT_NONE {
+		switch event.Keysym.Sym {
+		case sdl.K_RETURN:
+			ed.confirm(env)
+
+		case sdl.K_BACKSPACE:
+			if len(ed.text) > 0 {
+				ed.text = ed.text[:len(ed.text)-1]
+			}
+
+		case sdl.K_ESCAPE:
+			ed.typing = EDIT_NONE
+			ed.linkFrom = ""
+
+		default:
+		}
+
+		return nil
+	}
+
+	switch event.Keysym.Sym {
+	case sdl.K_e, sdl.K_ESCAPE:
+		ed.stop(env)
+
+	case sdl.K_F2:
+		if ed.selected != "" {
+			ed.startTyping(EDIT_RENAME, ed.selected)
+		}
+
+	case sdl.K_i:
+		if ed.selected != "" {
+			ed.def.InitialState = ed.selected
+		}
+
+	case sdl.K_f:
+		if ed.selected != "" {
+			ed.toggleFinal(ed.selected)
+		}
+
+	case sdl.K_DELETE:
+		if ed.selected != "" {
+			ed.deleteState(ed.selected)
+		}
+
+	case sdl.K_t:
+		ed.cycleType()
+
+	case sdl.K_F5:
+		name := filepath.Join(config.MachinesDir, NEW_MACHINE_FILE)
+		if env.machinePath != "" {
+			name = env.machinePath
+		}
+
+		ed.startTyping(EDIT_FILENAME, name)
+
+	default:
+	}
+
+	ed.refreshStates()
+	return nil
+}
+
+// Retorna true se o evento foi consumido pelo editor. O arraste dos estados
+// com o botão esquerdo continua sendo feito pelo handleMouseButtonEvents.
+func (ed *editor) handleMouse(event *sdl.MouseButtonEvent, env *environment) bool {
+	if ed.typing != EDIT_NONE {
+		return true
+	}
+
+	world := ui.camera.toWorld(env.w.toLogical(event.X, event.Y))
+	mousePos := &world
+	clicked := stateAt(mousePos)
+
+	switch event.Button {
+	case sdl.BUTTON_LEFT:
+		if event.Type != sdl.MOUSEBUTTONDOWN {
+			return false
+		}
+
+		if clicked == nil {
+			ed.selected = ""
+			if event.Clicks == 2 {
+				ed.addState(mousePos)
+				return true
+			}
+
+			return false
+		}
+
+		ed.selected = clicked.state
+		return false
+
+	case sdl.BUTTON_RIGHT:
+		switch event.Type {
+		case sdl.MOUSEBUTTONDOWN:
+			if clicked != nil {
+				ed.linkFrom = clicked.state
+			}
+
+		case sdl.MOUSEBUTTONUP:
+			if ed.linkFrom != "" && clicked != nil {
+				ed.linkTo = clicked.state
+				ed.startTyping(EDIT_LABEL, "")
+			} else {
+				ed.linkFrom = ""
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func (ed *editor) startTyping(mode int, text string) {
+	ed.typing = mode
+	ed.text = nil
+	for _, r := range text {
+		ed.text = append(ed.text, string(r))
+	}
+}
+
+func (ed *editor) confirm(env *environment) {
+	text := strings.TrimSpace(strings.Join(ed.text, ""))
+	mode := ed.typing
+	ed.typing = EDIT_NONE
+	ed.message = ""
+
+	var err error
+	switch mode {
+	case EDIT_RENAME:
+		err = ed.renameState(ed.selected, text)
+		if err == nil {
+			ed.selected = text
+		}
+
+	case EDIT_LABEL:
+		err = ed.setTransitions(ed.linkFrom, ed.linkTo, text)
+		ed.linkFrom = ""
+
+	case EDIT_FILENAME:
+		err = ed.save(env, text)
+
+	case EDIT_OVERWRITE:
+		if strings.ToLower(text) == "s" {
+			err = ed.write(env, ed.pending)
+		} else {
+			ed.message = "não salvo"
+		}
+
+		ed.pending = ""
+
+	default:
+	}
+
+	if err != nil {
+		ed.message = err.Error()
+	}
+
+	ed.refreshStates()
+}
+
+func (ed *editor) addState(pos *sdl.Point) {
+	var name string
+	for i := len(ed.def.States); ; i++ {
+		name = fmt.Sprintf("q%d", i)
+		if !utils.Contains(ed.def.States, name) {
+			break
+		}
+	}
+
+	ed.def.States = append(ed.def.States, name)
+	if ed.def.InitialState == "" {
+		ed.def.InitialState = name
+	}
+
+	rect := rectAt(machine.Position{X: pos.X, Y: pos.Y})
+	ui.states[name] = NewState(rect, name, COLOR_DEFAULT, nil)
+	ed.selected = name
+	ed.refreshStates()
+}
+
+func (ed *editor) deleteState(name string) {
+	ed.def.States = remove(ed.def.States, name)
+	ed.def.FinalStates = remove(ed.def.FinalStates, name)
+	if ed.def.InitialState == name {
+		ed.def.InitialState = ""
+	}
+
+	delete(ed.def.Transitions, name)
+	for from := range ed.def.Transitions {
+		ed.removeTransitions(from, name)
+	}
+
+	delete(ui.states, name)
+	ed.selected = ""
+}
+
+func (ed *editor) renameState(old, name string) error {
+	if name == "" || strings.ContainsAny(name, " ,()") {
+		return fmt.Errorf("nome de estado invalido: '%s'", name)
+	}
+
+	if name == old {
+		return nil
+	}
+
+	if utils.Contains(ed.def.States, name) {
+		return fmt.Errorf("o estado %s já existe", name)
+	}
+
+	replace := func(slice []string) {
+		for i := range slice {
+			if slice[i] == old {
+				slice[i] = name
+			}
+		}
+	}
+
+	replace(ed.def.States)
+	replace(ed.def.FinalStates)
+	if ed.def.InitialState == old {
+		ed.def.InitialState = name
+	}
+
+	if transitions, ok := ed.def.Transitions[old]; ok {
+		delete(ed.def.Transitions, old)
+		ed.def.Transitions[name] = transitions
+	}
+
+	for from, transitions := range ed.def.Transitions {
+		for i, t := range transitions {
+			label, to := splitTransition(t)
+			if to == old {
+				ed.def.Transitions[from][i] = joinTransition(label, name)
+			}
+		}
+	}
+
+	ui.states[name] = ui.states[old]
+	ui.states[name].state = name
+	delete(ui.states, old)
+	return nil
+}
+
+func (ed *editor) toggleFinal(name string) {
+	if utils.Contains(ed.def.FinalStates, name) {
+		ed.def.FinalStates = remove(ed.def.FinalStates, name)
+	} else {
+		ed.def.FinalStates = append(ed.def.FinalStates, name)
+	}
+}
+
+// Adiciona a transição de from para to com o rotulo digitado, sem o estado
+// destino. Ex: "a" em AFDs ou "a, &, b" em maquinas de uma pilha. Um rotulo
+// vazio remove as transições entre os dois estados.
+func (ed *editor) setTransitions(from, to, label string) error {
+	if label == "" {
+		ed.removeTransitions(from, to)
+		return nil
+	}
+
+	fields := strings.Split(label, ",")
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+		if fields[i] == "" {
+			return fmt.Errorf("rotulo com elemento vazio: '%s'", label)
+		}
+	}
+
+	machineType := ed.machineType()
+	if len(fields) != machine.TransitionArity(machineType)-1 {
+		return fmt.Errorf("transições de %s precisam de %d elementos antes do estado destino", ed.def.Type, machine.TransitionArity(machineType)-1)
+	}
+
+	transition := joinTransition(strings.Join(fields, ", "), to)
+	if utils.Contains(ed.def.Transitions[from], transition) {
+		return nil
+	}
+
+	ed.def.Transitions[from] = append(ed.def.Transitions[from], transition)
+
+	// O simbolo lido da fita entra no alfabeto
+	symbol := fields[0]
+	if symbol != collections.TAIL_FITA && symbol != collections.PALAVRA_VAZIA && !utils.Contains(ed.def.Alfabet, symbol) {
+		ed.def.Alfabet = append(ed.def.Alfabet, symbol)
+	}
+
+	return nil
+}
+
+func (ed *editor) removeTransitions(from, to string) {
+	var kept []string
+	for _, t := range ed.def.Transitions[from] {
+		if _, next := splitTransition(t); next != to {
+			kept = append(kept, t)
+		}
+	}
+
+	ed.def.Transitions[from] = kept
+}
+
+// O tipo só pode ser trocado sem transições, pois as tuplas mudam de tamanho
+func (ed *editor) cycleType() {
+	for _, transitions := range ed.def.Transitions {
+		if len(transitions) > 0 {
+			ed.message = "remova as transições antes de trocar o tipo"
+			return
+		}
+	}
+
+	ed.def.Type = machine.TypeName((ed.machineType() + 1) % (machine.TWO_STACK_MACHINE + 1))
+}
+
+func (ed *editor) machineType() int {
+	for _, t := range []int{machine.SIMPLE_MACHINE, machine.ONE_STACK_MACHINE, machine.TWO_STACK_MACHINE} {
+		if machine.TypeName(t) == ed.def.Type {
+			return t
+		}
+	}
+
+	return machine.SIMPLE_MACHINE
+}
+
+// Valida e salva a maquina no arquivo name. Um nome sem diretorio vai para
+// o diretorio de maquinas. Outro arquivo que já existe só é sobrescrito
+// depois da confirmação.
+func (ed *editor) save(env *environment, name string) error {
+	if name == "" {
+		return fmt.Errorf("nome de arquivo vazio")
+	}
+
+	if !strings.HasSuffix(strings.ToLower(name), ".json") {
+		name += ".json"
+	}
+
+	path := name
+	if filepath.Base(name) == name {
+		path = filepath.Join(config.MachinesDir, name)
+	}
+
+	path = filepath.Clean(path)
+	if _, err := os.Stat(path); err == nil && path != filepath.Clean(env.machinePath) {
+		ed.pending = path
+		ed.startTyping(EDIT_OVERWRITE, "")
+		return nil
+	}
+
+	return ed.write(env, path)
+}
+
+// Salva a maquina em path e a carrega no simulador
+func (ed *editor) write(env *environment, path string) error {
+	ed.def.Layout = statesLayout(ui.states)
+	m, err := ed.def.Machine(path)
+	if err != nil {
+		return err
+	}
+
+	err = reader.WriteMachine(ed.def, path)
+	if err != nil {
+		return err
+	}
+
+	err = env.loadMachine(m)
+	if err != nil {
+		return err
+	}
+
+	env.machinePath = path
+	env.inputPath = ""
+	env.watchFiles()
+	ui.init(env, true)
+	ed.message = fmt.Sprintf("salvo em %s", path)
+	return nil
+}
+
+// Recria os estados graficos a partir da definição, mantendo as posições
+func (ed *editor) refreshStates() {
+	if !ed.active {
+		return
+	}
+
+	states := make(map[string]*graphicalState)
+	for _, name := range ed.def.States {
+		rect := ed.placeState(name)
+		if previus, ok := ui.states[name]; ok {
+			rect = previus.Rect
+		}
+
+		var statesKeys []string
+		labels := make(map[string][]string)
+		for _, t := range ed.def.Transitions[name] {
+			label, to := splitTransition(t)
+			statesKeys = append(statesKeys, to)
+			labels[to] = append(labels[to], label)
+		}
+
+		states[name] = NewState(rect, name, COLOR_DEFAULT, statesKeys)
+		states[name].initial = name == ed.def.InitialState
+		states[name].final = utils.Contains(ed.def.FinalStates, name)
+		states[name].labels = labels
+	}
+
+	ui.states = states
+}
+
+// Posição de um estado que ainda não esta no diagrama: a salva no layout
+// da maquina ou, sem ela, a do cursor
+func (ed *editor) placeState(name string) *sdl.Rect {
+	if position, ok := ed.def.Layout[name]; ok {
+		return rectAt(position)
+	}
+
+	mouse := ui.dragInfo.mousePos
+	return rectAt(machine.Position{X: mouse.X, Y: mouse.Y})
+}
+
+func (ed *editor) colorStates() {
+	if state, ok := ui.states[ed.selected]; ok {
+		state.Color = COLOR_INITIAL
+	}
+
+	if state, ok := ui.states[ed.linkFrom]; ok {
+		state.Color = COLOR_ACCEPTED
+	}
+}
+
+func (ed *editor) draw(window *_SDLWindow) error {
+	// Linha da transição sendo desenhada
+	if from, ok := ui.states[ed.linkFrom]; ok && ed.typing == EDIT_NONE {
+		center := from.Center()
+		mouse := ui.dragInfo.mousePos
+		gfx.ThickLineColor(window.renderer, center.X, center.Y, mouse.X, mouse.Y, 2, COLOR_ACCEPTED)
+	}
+
+	text := []string{fmt.Sprintf("EDIÇÃO: %s", ed.def.Type)}
+	switch ed.typing {
+	case EDIT_RENAME:
+		text = append(text, "nome: "+strings.Join(ed.text, "")+"_")
+	case EDIT_LABEL:
+		text = append(text, fmt.Sprintf("%s -> %s: %s_", ed.linkFrom, ed.linkTo, strings.Join(ed.text, "")))
+	case EDIT_FILENAME:
+		text = append(text, "arquivo: "+strings.Join(ed.text, "")+"_")
+	case EDIT_OVERWRITE:
+		text = append(text, fmt.Sprintf("sobrescrever %s? (s/n): %s_", filepath.Base(ed.pending), strings.Join(ed.text, "")))
+	default:
+		text = append(text, editorHelp...)
+	}
+
+	if ed.message != "" {
+		text = append(text, ed.message)
+	}
+
+	maxLen := 40
+	var spaceBetween int32 = DIMENSAO_ESTRUTURAS / 2
+	return drawText(window, text, spaceBetween, PADX, PADY+DIMENSAO_ESTRUTURAS/2, maxLen, TEXT_DOWN_LEFT)
+}
+
+func stateAt(pos *sdl.Point) *graphicalState {
+	for _, state := range ui.states {
+		if pos.InRect(state.Rect) {
+			return state
+		}
+	}
+
+	return nil
+}
+
+// Separa "(a, &, b, q1)" em "a, &, b" e "q1"
+func splitTransition(t string) (string, string) {
+	inner := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(t), "("), ")")
+	i := strings.LastIndex(inner, ",")
+	if i < 0 {
+		return "", strings.TrimSpace(inner)
+	}
+
+	return strings.TrimSpace(inner[:i]), strings.TrimSpace(inner[i+1:])
+}
+
+func joinTransition(label, to string) string {
+	return fmt.Sprintf("(%s, %s)", label, to)
+}
+
+func remove(slice []string, value string) []string {
+	var result []string
+	for _, v := range slice {
+		if v != value {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,207 @@
+package graphics
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	imagedraw "image/draw"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+	"unsafe"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Exportação da computação atual como animação, para usar em slides. Cada
+// passo é desenhado fora da tela (em uma textura) com o diagrama, a fita,
+// as pilhas e o historico, como na janela, e o resultado vira um GIF
+// animado (tecla g) ou uma sequencia de PNGs numerados (tecla p). O tempo de
+// cada quadro é o delayAnimation atual.
+
+const (
+	EXPORT_GIF = iota
+	EXPORT_PNG = iota
+)
+
+// Recebe cada quadro assim que ele é desenhado. O quadro é reaproveitado
+// no passo seguinte, então ele não pode ser guardado.
+type frameWriter func(index int, frame *image.RGBA) error
+
+func (env *environment) exportComputation(format int) error {
+	err := os.MkdirAll(config.ExportsDir, 0755)
+	if err != nil {
+		return fmt.Errorf("erro ao criar o diretorio %s. err: %s", config.ExportsDir, err)
+	}
+
+	path := filepath.Join(config.ExportsDir, exportName(env.machinePath))
+	switch format {
+	case EXPORT_GIF:
+		path += ".gif"
+		err = env.exportGif(path)
+	case EXPORT_PNG:
+		err = os.MkdirAll(path, 0755)
+		if err != nil {
+			return &fileError{path: path, err: err}
+		}
+
+		err = env.renderFrames(func(index int, frame *image.RGBA) error {
+			return writePng(path, index, frame)
+		})
+	default:
+		return fmt.Errorf("formato de exportação invalido: %d", format)
+	}
+
+	if err != nil {
+		return &fileError{path: path, err: err}
+	}
+
+	ui.setStatus(fmt.Sprintf("Exportado: %s", filepath.Base(path)))
+	return nil
+}
+
+// Desenha cada passo da computação em uma textura e passa os pixels para
+// write. O passo atual e a animação são restaurados no final.
+func (env *environment) renderFrames(write frameWriter) error {
+	w := env.w
+	width := int32(math.Round(float64(w.WIDTH) * w.scale))
+	height := int32(math.Round(float64(w.HEIGHT) * w.scale))
+
+	target, err := w.renderer.CreateTexture(sdl.PIXELFORMAT_RGBA32, sdl.TEXTUREACCESS_TARGET, width, height)
+	if err != nil {
+		return err
+	}
+
+	defer target.Destroy()
+
+	err = w.renderer.SetRenderTarget(target)
+	if err != nil {
+		return err
+	}
+
+	// A escala da janela vale também para a textura
+	defer w.renderer.SetScale(float32(w.scale), float32(w.scale))
+	defer w.renderer.SetRenderTarget(nil)
+	err = w.renderer.SetScale(float32(w.scale), float32(w.scale))
+	if err != nil {
+		return err
+	}
+
+	index, running := ui.indexComputation, env.running
+	defer func() {
+		ui.indexComputation, env.running = index, running
+	}()
+
+	env.running = false
+	frame := image.NewRGBA(image.Rect(0, 0, int(width), int(height)))
+	for i := 0; i <= ui.lastIndex(); i++ {
+		ui.indexComputation = i
+		err = env.renderFrame(frame)
+		if err != nil {
+			return err
+		}
+
+		err = write(i, frame)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (env *environment) renderFrame(frame *image.RGBA) error {
+	ui.update(env)
+	err := env.w.cleanUp()
+	if err != nil {
+		return err
+	}
+
+	err = drawNodes(env)
+	if err != nil {
+		return err
+	}
+
+	err = drawUi(env)
+	if err != nil {
+		return err
+	}
+
+	return env.w.renderer.ReadPixels(nil, sdl.PIXELFORMAT_RGBA32, unsafe.Pointer(&frame.Pix[0]), frame.Stride)
+}
+
+// Nome da maquina seguido da data, para não sobrescrever exportações
+func exportName(machinePath string) string {
+	return fmt.Sprintf("%s-%s", machineName(machinePath), time.Now().Format("20060102-150405"))
+}
+
+// Os quadros são codificados assim que são desenhados, sem guardar a
+// animação na memoria
+func (env *environment) exportGif(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	// delayAnimation é em segundos e o GIF guarda centesimos
+	delay := int(math.Round(delayAnimation * 100))
+	if delay < 1 {
+		delay = 1
+	}
+
+	var gw *gifWriter
+	err = env.renderFrames(func(index int, frame *image.RGBA) error {
+		if gw == nil {
+			gw = newGifWriter(file, frame.Rect.Dx(), frame.Rect.Dy())
+		}
+
+		paletted := image.NewPaletted(frame.Bounds(), framePalette(frame))
+		imagedraw.Draw(paletted, frame.Bounds(), frame, image.Point{}, imagedraw.Src)
+		return gw.writeFrame(paletted, delay)
+	})
+	if err != nil {
+		return err
+	}
+
+	return gw.close()
+}
+
+// A interface usa poucas cores, então na maioria das vezes elas cabem na
+// paleta do quadro sem perdas. Se não couberem é usada a paleta Plan9. Cada
+// quadro do GIF pode ter a sua propria paleta.
+func framePalette(frame *image.RGBA) color.Palette {
+	seen := make(map[color.RGBA]bool)
+	var colors color.Palette
+	for i := 0; i < len(frame.Pix); i += 4 {
+		c := color.RGBA{R: frame.Pix[i], G: frame.Pix[i+1], B: frame.Pix[i+2], A: 255}
+		if seen[c] {
+			continue
+		}
+
+		if len(colors) == 256 {
+			return palette.Plan9
+		}
+
+		seen[c] = true
+		colors = append(colors, c)
+	}
+
+	return colors
+}
+
+// Os quadros ficam em um diretorio proprio: quadro_000.png, quadro_001.png...
+func writePng(dir string, index int, frame *image.RGBA) error {
+	file, err := os.Create(filepath.Join(dir, fmt.Sprintf("quadro_%03d.png", index)))
+	if err != nil {
+		return err
+	}
+
+	err = png.Encode(file, frame)
+	file.Close()
+	return err
+}
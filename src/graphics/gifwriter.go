@@ -0,0 +1,147 @@
+package graphics
+
+import (
+	"bufio"
+	"compress/lzw"
+	"encoding/binary"
+	"image"
+	"io"
+)
+
+// Escreve um GIF animado quadro a quadro. O image/gif só codifica a
+// animação inteira de uma vez, o que exigiria guardar todos os quadros de
+// uma computação longa na memoria. Cada quadro tem a sua propria paleta.
+
+type gifWriter struct {
+	w             *bufio.Writer
+	width, height int
+}
+
+// Escreve o cabeçalho da animação, que repete sem fim
+func newGifWriter(w io.Writer, width, height int) *gifWriter {
+	gw := &gifWriter{w: bufio.NewWriter(w), width: width, height: height}
+	gw.w.WriteString("GIF89a")
+
+	// Tamanho da tela, sem paleta global
+	gw.writeUint16(width)
+	gw.writeUint16(height)
+	gw.w.Write([]byte{0x00, 0x00, 0x00})
+
+	// Extensão NETSCAPE2.0 para repetir a animação
+	gw.w.Write([]byte{0x21, 0xff, 0x0b})
+	gw.w.WriteString("NETSCAPE2.0")
+	gw.w.Write([]byte{0x03, 0x01, 0x00, 0x00, 0x00})
+	return gw
+}
+
+// Escreve o quadro com a paleta dele. delay em centesimos de segundo.
+func (gw *gifWriter) writeFrame(frame *image.Paletted, delay int) error {
+	// Tamanho da paleta: potencia de 2, entre 2 e 256 cores
+	bits := 1
+	for 1<<bits < len(frame.Palette) {
+		bits++
+	}
+
+	// Extensão de controle com o tempo do quadro
+	gw.w.Write([]byte{0x21, 0xf9, 0x04, 0x00})
+	gw.writeUint16(delay)
+	gw.w.Write([]byte{0x00, 0x00})
+
+	// Descritor da imagem com a paleta local
+	gw.w.WriteByte(0x2c)
+	gw.writeUint16(0)
+	gw.writeUint16(0)
+	gw.writeUint16(gw.width)
+	gw.writeUint16(gw.height)
+	gw.w.WriteByte(0x80 | byte(bits-1))
+
+	table := make([]byte, 3<<bits)
+	for i, c := range frame.Palette {
+		r, g, b, _ := c.RGBA()
+		table[i*3], table[i*3+1], table[i*3+2] = byte(r>>8), byte(g>>8), byte(b>>8)
+	}
+
+	gw.w.Write(table)
+
+	// O LZW do GIF usa no minimo 2 bits
+	litWidth := bits
+	if litWidth < 2 {
+		litWidth = 2
+	}
+
+	gw.w.WriteByte(byte(litWidth))
+	blocks := &gifBlocks{w: gw.w}
+	compressor := lzw.NewWriter(blocks, lzw.LSB, litWidth)
+	for y := 0; y < gw.height; y++ {
+		start := y * frame.Stride
+		_, err := compressor.Write(frame.Pix[start : start+gw.width])
+		if err != nil {
+			return err
+		}
+	}
+
+	err := compressor.Close()
+	if err != nil {
+		return err
+	}
+
+	return blocks.close()
+}
+
+// Escreve o fim da animação
+func (gw *gifWriter) close() error {
+	gw.w.WriteByte(0x3b)
+	return gw.w.Flush()
+}
+
+func (gw *gifWriter) writeUint16(value int) {
+	var buffer [2]byte
+	binary.LittleEndian.PutUint16(buffer[:], uint16(value))
+	gw.w.Write(buffer[:])
+}
+
+// Divide os dados do LZW em blocos de até 255 bytes, precedidos pelo
+// tamanho, como o GIF exige
+type gifBlocks struct {
+	w      *bufio.Writer
+	buffer [255]byte
+	n      int
+}
+
+func (b *gifBlocks) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		copied := copy(b.buffer[b.n:], p)
+		b.n += copied
+		p = p[copied:]
+		if b.n == len(b.buffer) {
+			err := b.flush()
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return written, nil
+}
+
+func (b *gifBlocks) flush() error {
+	if b.n == 0 {
+		return nil
+	}
+
+	b.w.WriteByte(byte(b.n))
+	_, err := b.w.Write(b.buffer[:b.n])
+	b.n = 0
+	return err
+}
+
+// Escreve o ultimo bloco e o terminador
+func (b *gifBlocks) close() error {
+	err := b.flush()
+	if err != nil {
+		return err
+	}
+
+	return b.w.WriteByte(0x00)
+}
@@ -2,13 +2,13 @@ package graphics
 
 import (
 	"autosimulator/src/collections"
+	"autosimulator/src/layout"
 	"autosimulator/src/machine"
-	"autosimulator/src/machine/oneStackMachine"
-	"autosimulator/src/machine/twoStackMachine"
 	"autosimulator/src/reader"
-	"autosimulator/src/utils"
+	"autosimulator/src/theme"
+	"errors"
 	"fmt"
-	"path/filepath"
+	"math"
 	"runtime"
 	"unicode/utf8"
 
@@ -18,9 +18,10 @@ import (
 
 const (
 	TITLE         = "Simulador de Autômato"
-	FONT_PATH     = "src/graphics/assets/IBMPlexMono-ExtraLight.ttf"
+	DEFAULT_FONT  = "IBMPlexMono-ExtraLight.ttf"
 	EXAMPLES_PATH = "machines"
 	INPUT_PATH    = "inputs"
+	EXPORTS_PATH  = "exports"
 	FONT_SIZE     = 24
 	FPS_DEFAULT   = 60
 	WITDH, HEIGHT = 580, 750
@@ -28,21 +29,42 @@ const (
 
 type (
 	environment struct {
-		w         *_SDLWindow
-		machine   machine.Machine
-		input     *collections.Fita
+		w           *_SDLWindow
+		machine     machine.Machine
+		machinePath string
+		input       *collections.Fita
+
+		// Entrada default da maquina carregada. O Init da maquina troca a
+		// entrada dela pela ultima executada, então ela é guardada aqui.
+		defaultInput *collections.Fita
+
+		// Arquivo da entrada carregada pelo menu. Vazio para a entrada
+		// default ou digitada.
+		inputPath string
+		watcher   watcher
+
 		terminate bool
 		running   bool
 		typing    bool
+
+		// Modo de verificação do alfabeto das entradas
+		alfabetMode int
 	}
 
 	_SDLWindow struct {
 		window     *sdl.Window
 		renderer   *sdl.Renderer
 		font       *ttf.Font
-		WIDTH      int32
-		HEIGHT     int32
 		cacheWords map[string]*sdl.Surface
+
+		// Tamanho logico da janela, usado por todos os desenhos. A janela
+		// pode ser redimensionada: o conteudo é ampliado por uiScale para
+		// manter a proporção do tamanho padrão, e scale inclui também a
+		// densidade de pixels da tela (hi-DPI).
+		WIDTH   int32
+		HEIGHT  int32
+		uiScale float64
+		scale   float64
 	}
 
 	uiComponents struct {
@@ -52,13 +74,52 @@ type (
 		menuMode          bool
 		menuInfo          *menu
 		dragInfo          *drag
+		editor            *editor
+		camera            *camera
+
+		// Erro mostrado na tela até ser fechado. nil quando não há erro.
+		errorPanel *errorPanel
+
+		// Aviso curto no canto da tela, como a troca do modo do alfabeto
+		status statusMessage
+
+		// Transição feita no passo atual da computação. nil no passo inicial.
+		fired *firedTransition
+
+		// Arvore de computação, aberta com a tecla t
+		tree *treeView
+
+		// Painel da fita, com a entrada inteira
+		tape *tapePanel
+
+		// Segunda maquina da comparação lado a lado. nil fora da comparação.
+		compare *comparison
+
+		// Execução em lote de um CSV, aberta com a tecla b
+		batch *batchPanel
+
+		// Transições do estado sob o mouse
+		tooltip *tooltip
+
+		// Estados e transições em que a animação automatica pausa
+		breaks *breakpoints
+
+		// Barra com o passo atual, acima da fita
+		timeline *timeline
 		computationHist
-		*stackHist
 	}
 
 	menu struct {
 		currentMenu *SelectBox
 		menus       map[string]*SelectBox
+
+		// O explorer escolhe a maquina da comparação em vez de trocar a
+		// maquina carregada
+		compare bool
+
+		// Ignora o texto da tecla que abriu um menu de arquivos, que iria
+		// para o filtro
+		skipText bool
 	}
 
 	drag struct {
@@ -66,16 +127,32 @@ type (
 		selected      *graphicalState
 		leftMouseDown bool
 		mousePos      *sdl.Point
+
+		// Se o estado selecionado foi arrastado
+		moved bool
 	}
 
-	computationHist struct {
-		indexComputation int
-		bufferInput      []string
+	errorPanel struct {
+		message string
+		path    string
+	}
+
+	// Erro ao ler um arquivo. O caminho é mostrado no painel de erro.
+	fileError struct {
+		path string
+		err  error
+	}
+
+	firedTransition struct {
+		from   string
+		to     string
+		label  string
+		symbol string
+		color  sdl.Color
 	}
 
-	stackHist struct {
-		stackA [][]string
-		stackB [][]string
+	computationHist struct {
+		indexComputation int
 	}
 )
 
@@ -83,9 +160,9 @@ var (
 	main = &SelectBox{
 		Name:         "main",
 		CurrentIndex: 1,
-		MaxItems:     4,
+		MaxItems:     6,
 		MaxLen:       13,
-		Options:      []string{"Machines", "New Input", "Save Input", "Load Input"},
+		Options:      []string{"Machines", "New Input", "Save Input", "Load Input", "Compare", "Batch Input"},
 	}
 
 	menus = map[string]*SelectBox{
@@ -96,6 +173,7 @@ var (
 			MaxItems:     14,
 			MaxLen:       25,
 			Options:      nil,
+			Files:        &fileBrowser{ext: ".json"},
 		},
 		"input": {
 			Name: "input",
@@ -106,6 +184,15 @@ var (
 			MaxItems:     14,
 			MaxLen:       25,
 			Options:      nil,
+			Files:        &fileBrowser{ext: ".csv"},
+		},
+		"batch_input": {
+			Name:         "batch_input",
+			CurrentIndex: 1,
+			MaxItems:     14,
+			MaxLen:       25,
+			Options:      nil,
+			Files:        &fileBrowser{ext: ".csv"},
 		},
 	}
 
@@ -114,10 +201,14 @@ var (
 			currentMenu: menus["main"],
 			menus:       menus,
 		},
-		stackHist: &stackHist{
-			[][]string{},
-			[][]string{},
-		},
+		editor:   &editor{},
+		tree:     &treeView{},
+		tape:     &tapePanel{},
+		batch:    &batchPanel{selected: -1},
+		tooltip:  &tooltip{},
+		breaks:   newBreakpoints(nil),
+		timeline: &timeline{},
+		camera:   newCamera(),
 	}
 
 	fpsTimer       uint64
@@ -129,18 +220,31 @@ var (
 	RED   = sdl.Color{R: 255, G: 0, B: 0, A: 255}
 	PINK  = sdl.Color{R: 255, G: 80, B: 80, A: 255}
 	GREEN = sdl.Color{R: 0, G: 255, B: 0, A: 255}
+	GREY  = sdl.Color{R: 110, G: 110, B: 110, A: 255}
 
+	// Cores da interface. Trocadas pelo tema (theme.go).
 	COLOR_DEFAULT   = sdl.Color{R: 235, G: 174, B: 52, A: 255}
 	COLOR_BACKGROUD = sdl.Color{R: 18, G: 18, B: 18, A: 255}
+	COLOR_INITIAL   = BLUE
+	COLOR_CURRENT   = RED
+	COLOR_ACCEPTED  = GREEN
+	COLOR_REJECTED  = RED
+	COLOR_WARNING   = PINK
+	COLOR_ERROR     = RED
+	COLOR_CONSUMED  = GREY
+	COLOR_HIGHLIGHT = WHITE
 
 	typedInput []string
 )
 
 func Mainloop(env *environment) {
 	runtime.LockOSThread() // sdl2 precisa rodar na main thread.
+	ui.breaks = newBreakpoints(config.Breakpoints)
 	ui.init(env, true)
+	env.watchFiles()
 	for !env.terminate {
 		pollEvent(env)
+		env.checkReload()
 		draw(env)
 		sdl.Delay(1000 / FPS_DEFAULT)
 	}
@@ -149,28 +253,70 @@ func Mainloop(env *environment) {
 
 func PopulateEnvironment(window *_SDLWindow, activeMachine machine.Machine) *environment {
 	env := &environment{
-		w:         window,
-		machine:   activeMachine,
-		terminate: false,
-		running:   false,
-		input:     activeMachine.GetInput(),
+		w:            window,
+		machine:      activeMachine,
+		terminate:    false,
+		running:      false,
+		input:        activeMachine.GetInput(),
+		defaultInput: activeMachine.GetInput(),
 	}
 
 	return env
 }
 
-func NewSDLWindow() *_SDLWindow {
-	err := sdl.Init(sdl.INIT_EVERYTHING)
+// Abre a maquina e, opcionalmente, a entrada (CSV). Os arquivos passam a
+// ser observados pela recarga automatica.
+func (env *environment) OpenFiles(machinePath, inputPath string) error {
+	m, err := reader.ReadMachine(machinePath)
+	if err != nil {
+		return err
+	}
+
+	err = env.loadMachine(m)
+	if err != nil {
+		return &fileError{path: machinePath, err: err}
+	}
+
+	env.machinePath = machinePath
+	if inputPath != "" {
+		input, err := reader.ReadInput(inputPath)
+		if err != nil {
+			return &fileError{path: inputPath, err: err}
+		}
+
+		err = env.setInput(input)
+		if err != nil {
+			return &fileError{path: inputPath, err: err}
+		}
+
+		env.inputPath = inputPath
+	}
+
+	return nil
+}
+
+func NewSDLWindow(cfg Config) *_SDLWindow {
+	config = DefaultConfig().Merge(cfg)
+
+	// Um tema invalido não impede a janela de abrir
+	err := initTheme(config.Theme)
+	if err != nil {
+		ui.errorPanel = &errorPanel{message: fmt.Sprintf("%s. Usando o tema %s", err, theme.THEME_DARK)}
+	}
+
+	err = sdl.Init(sdl.INIT_EVERYTHING)
 	if err != nil {
 		panic(err)
 	}
 
 	window, err := sdl.CreateWindow(TITLE, sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED,
-		WITDH, HEIGHT, sdl.WINDOW_SHOWN)
+		config.Width, config.Height, sdl.WINDOW_SHOWN|sdl.WINDOW_RESIZABLE|sdl.WINDOW_ALLOW_HIGHDPI)
 	if err != nil {
 		panic(err)
 	}
 
+	window.SetMinimumSize(WITDH/2, HEIGHT/2)
+
 	renderer, err := sdl.CreateRenderer(window, -1, sdl.RENDERER_ACCELERATED)
 	if err != nil {
 		panic(err)
@@ -181,20 +327,78 @@ func NewSDLWindow() *_SDLWindow {
 		panic(err)
 	}
 
-	font, err := ttf.OpenFont(FONT_PATH, FONT_SIZE)
+	cacheWords := make(map[string]*sdl.Surface)
+
+	w := &_SDLWindow{window: window,
+		renderer:   renderer,
+		cacheWords: cacheWords,
+	}
+
+	err = w.resize()
 	if err != nil {
 		panic(err)
 	}
 
-	cacheWords := make(map[string]*sdl.Surface)
+	return w
+}
 
-	return &_SDLWindow{window: window,
-		renderer:   renderer,
-		font:       font,
-		cacheWords: cacheWords,
-		WIDTH:      WITDH,
-		HEIGHT:     HEIGHT,
+// Recalcula o tamanho logico e as escalas a partir do tamanho atual da
+// janela. A fonte é reaberta no tamanho em pixels para o texto continuar
+// nitido.
+func (w *_SDLWindow) resize() error {
+	pointsW, pointsH := w.window.GetSize()
+	pixelsW, _, err := w.renderer.GetOutputSize()
+	if err != nil {
+		return err
+	}
+
+	dpi := float64(pixelsW) / float64(pointsW)
+	uiScale := math.Min(float64(pointsW)/WITDH, float64(pointsH)/HEIGHT)
+	scale := dpi * uiScale
+
+	w.WIDTH = int32(float64(pointsW) / uiScale)
+	w.HEIGHT = int32(float64(pointsH) / uiScale)
+	w.uiScale = uiScale
+	err = w.renderer.SetScale(float32(scale), float32(scale))
+	if err != nil {
+		return err
+	}
+
+	if w.font != nil && scale == w.scale {
+		return nil
+	}
+
+	font, err := openFont(int(math.Round(FONT_SIZE * scale)))
+	if err != nil {
+		return err
 	}
+
+	if w.font != nil {
+		w.font.Close()
+	}
+
+	// As palavras em cache foram renderizadas na fonte anterior
+	for key, surface := range w.cacheWords {
+		surface.Free()
+		delete(w.cacheWords, key)
+	}
+
+	w.font = font
+	w.scale = scale
+	return nil
+}
+
+// Converte a posição do mouse (em pontos da janela) para o tamanho logico
+func (w *_SDLWindow) toLogical(x, y int32) sdl.Point {
+	return sdl.Point{
+		X: int32(float64(x) / w.uiScale),
+		Y: int32(float64(y) / w.uiScale),
+	}
+}
+
+// Tamanho logico de um texto renderizado na fonte em pixels
+func (w *_SDLWindow) textSize(surface *sdl.Surface) (int32, int32) {
+	return int32(float64(surface.W) / w.scale), int32(float64(surface.H) / w.scale)
 }
 
 func (env *environment) Destroy() {
@@ -218,21 +422,60 @@ func pollEvent(env *environment) {
 			env.Quit()
 
 		case *sdl.KeyboardEvent:
+			// Qualquer tecla fecha o painel de erro
+			if ui.errorPanel != nil {
+				if event.Type == sdl.KEYDOWN {
+					ui.errorPanel = nil
+				}
+
+				break
+			}
+
 			err := handleKeyboardEvents(event, env)
 			if err != nil {
 				env.throw(err)
 			}
 
 		case *sdl.MouseButtonEvent:
+			// Assim como qualquer clique
+			if ui.errorPanel != nil {
+				if event.Type == sdl.MOUSEBUTTONDOWN {
+					ui.errorPanel = nil
+				}
+
+				break
+			}
+
 			handleMouseButtonEvents(event, env)
 
 		case *sdl.MouseMotionEvent:
 			handleMouseMotionEvent(env)
 
+		case *sdl.MouseWheelEvent:
+			handleMouseWheelEvent(event, env)
+
+		case *sdl.WindowEvent:
+			if event.Event == sdl.WINDOWEVENT_SIZE_CHANGED {
+				err := env.w.resize()
+				if err != nil {
+					env.throw(err)
+				}
+			}
+
 		case *sdl.TextInputEvent:
-			if env.typing {
+			if ui.editor.typing != EDIT_NONE {
+				r, _ := utf8.DecodeRune(event.Text[:])
+				ui.editor.text = append(ui.editor.text, string(r))
+			} else if env.typing {
 				r, _ := utf8.DecodeRune(event.Text[:])
 				typedInput = append(typedInput, string(r))
+			} else if ui.menuMode && ui.menuInfo.currentMenu.Files != nil {
+				if ui.menuInfo.skipText {
+					ui.menuInfo.skipText = false
+					break
+				}
+
+				ui.menuInfo.currentMenu.typeFilter(event.GetText())
 			}
 
 		default:
@@ -249,11 +492,30 @@ func handleKeyboardEvents(event *sdl.KeyboardEvent, env *environment) error {
 		return err
 	}
 
+	ui.menuInfo.skipText = false
+
+	// Enquadra o diagrama na janela, exceto durante a digitação
+	if event.Keysym.Sym == sdl.K_0 && !env.typing && !ui.menuMode && ui.editor.typing == EDIT_NONE {
+		ui.camera.fit(ui.diagramStates(), env.w)
+		return nil
+	}
+
+	// O editor trata todas as teclas enquanto estiver ativo
+	if ui.editor.active {
+		return ui.editor.handleKey(event, env)
+	}
+
 	// Handle digitação
 	if env.typing {
 		switch event.Keysym.Sym {
 		case sdl.K_RETURN:
-			env.input = collections.FitaFromArray(typedInput)
+			err = env.setInput(collections.FitaFromArray(typedInput))
+			if err != nil {
+				return err
+			}
+
+			env.inputPath = ""
+			env.watchFiles()
 			ui.init(env, false)
 
 		case sdl.K_BACKSPACE:
@@ -281,12 +543,30 @@ func handleKeyboardEvents(event *sdl.KeyboardEvent, env *environment) error {
 		case sdl.K_DOWN:
 			menu.CurrentIndex++
 
+		case sdl.K_PAGEUP:
+			menu.CurrentIndex -= menu.MaxItems
+
+		case sdl.K_PAGEDOWN:
+			menu.CurrentIndex += menu.MaxItems
+
 		case sdl.K_RETURN:
 			err = ui.changeMenu(env)
 
-		case sdl.K_m:
+		case sdl.K_ESCAPE:
 			ui.closeMenus(env)
 
+		case sdl.K_BACKSPACE:
+			if menu.Files != nil {
+				menu.Files.backspace()
+				menu.reload()
+			}
+
+		case sdl.K_m:
+			// Nos menus de arquivos as letras vão para o filtro
+			if menu.Files == nil {
+				ui.closeMenus(env)
+			}
+
 		default:
 		}
 
@@ -301,6 +581,18 @@ func handleKeyboardEvents(event *sdl.KeyboardEvent, env *environment) error {
 	case sdl.K_UP:
 		ui.previusComputation()
 
+	case sdl.K_PAGEDOWN:
+		ui.goToStep(ui.indexComputation + TIMELINE_SKIP)
+
+	case sdl.K_PAGEUP:
+		ui.goToStep(ui.indexComputation - TIMELINE_SKIP)
+
+	case sdl.K_HOME:
+		ui.goToStep(0)
+
+	case sdl.K_END:
+		ui.goToStep(ui.lastIndex())
+
 	case sdl.K_SPACE:
 		// toggle running
 		env.running = !env.running
@@ -312,10 +604,54 @@ func handleKeyboardEvents(event *sdl.KeyboardEvent, env *environment) error {
 		ui.menuMode = !ui.menuMode
 
 	case sdl.K_EQUALS:
-		delayAnimation += 0.1
+		changeDelay(0.1)
 
 	case sdl.K_MINUS:
-		delayAnimation -= 0.1
+		changeDelay(-0.1)
+
+	case sdl.K_a:
+		env.toggleAlfabetMode()
+
+	case sdl.K_k:
+		err = nextTheme()
+
+	case sdl.K_e:
+		// O editor usa o diagrama inteiro
+		if ui.compare != nil {
+			ui.stopComparison(env)
+		}
+
+		ui.editor.start(env)
+
+	case sdl.K_c:
+		if ui.compare != nil {
+			ui.stopComparison(env)
+		} else {
+			ui.menuMode = true
+			ui.menuInfo.currentMenu = ui.menuInfo.menus["explorer"]
+			ui.menuInfo.compare = true
+			ui.menuInfo.skipText = true
+		}
+
+	case sdl.K_t:
+		ui.batch.active = false
+		ui.tree.toggle(env)
+
+	case sdl.K_b:
+		ui.tree.active = false
+		if ui.batch.rows == nil {
+			ui.menuMode = true
+			ui.menuInfo.currentMenu = ui.menuInfo.menus["batch_input"]
+			ui.menuInfo.skipText = true
+		} else {
+			ui.batch.toggle()
+		}
+
+	case sdl.K_g:
+		err = env.exportComputation(EXPORT_GIF)
+
+	case sdl.K_p:
+		err = env.exportComputation(EXPORT_PNG)
 
 	default:
 	}
@@ -327,8 +663,17 @@ func (ui *uiComponents) closeMenus(env *environment) {
 	ui.menuInfo.currentMenu.CurrentIndex = 1
 	ui.menuInfo.currentMenu = ui.menuInfo.menus["main"]
 	ui.menuInfo.currentMenu.CurrentIndex = 1
+	ui.menuInfo.compare = false
 	ui.menuMode = false
 	env.stopTyping()
+
+	// Os menus de arquivos lembram o diretorio, mas não o filtro
+	for _, menu := range ui.menuInfo.menus {
+		if menu.Files != nil && menu.Files.filter != "" {
+			menu.Files.filter = ""
+			menu.reload()
+		}
+	}
 }
 
 func (ui *uiComponents) changeMenu(env *environment) error {
@@ -355,31 +700,74 @@ func (ui *uiComponents) changeMenu(env *environment) error {
 		case 4: // LOAD INPUT
 			ui.menuInfo.currentMenu = ui.menuInfo.menus["load_input"]
 
+		case 5: // COMPARE
+			ui.menuInfo.currentMenu = ui.menuInfo.menus["explorer"]
+			ui.menuInfo.compare = true
+
+		case 6: // BATCH INPUT
+			ui.menuInfo.currentMenu = ui.menuInfo.menus["batch_input"]
+
 		default:
 		}
 
 	case "explorer":
-		selectedPath := ui.menuInfo.currentMenu.CurrentIndex
-		m, err := reader.ReadMachine(filepath.Join(EXAMPLES_PATH, ui.menuInfo.currentMenu.Options[selectedPath-1]))
+		path, ok := ui.menuInfo.currentMenu.pick()
+		if !ok {
+			return nil
+		}
+
+		if ui.menuInfo.compare {
+			return ui.startComparison(env, path)
+		}
+
+		m, err := reader.ReadMachine(path)
 		if err != nil {
-			return err
+			return &fileError{path: path, err: err}
+		}
+
+		err = env.loadMachine(m)
+		if err != nil {
+			return &fileError{path: path, err: err}
 		}
 
-		ui.menuInfo.currentMenu.Options = nil
-		env.loadMachine(m)
+		// Os breakpoints marcados eram da maquina anterior
+		ui.breaks = newBreakpoints(config.Breakpoints)
+		env.machinePath = path
+		env.inputPath = ""
+		env.watchFiles()
 		ui.init(env, true)
 
 	case "load_input":
-		selectedPath := ui.menuInfo.currentMenu.CurrentIndex
-		i, err := reader.ReadInput(filepath.Join(INPUT_PATH, ui.menuInfo.currentMenu.Options[selectedPath-1]))
+		path, ok := ui.menuInfo.currentMenu.pick()
+		if !ok {
+			return nil
+		}
+
+		i, err := reader.ReadInput(path)
 		if err != nil {
-			return err
+			return &fileError{path: path, err: err}
+		}
+
+		err = env.setInput(i)
+		if err != nil {
+			return &fileError{path: path, err: err}
 		}
 
-		ui.menuInfo.currentMenu.Options = nil
-		env.input = i
+		env.inputPath = path
+		env.watchFiles()
+
 		ui.init(env, false)
 
+	case "batch_input":
+		path, ok := ui.menuInfo.currentMenu.pick()
+		if !ok {
+			return nil
+		}
+
+		ui.closeMenus(env)
+		ui.tree.active = false
+		return ui.batch.load(env, path)
+
 	default:
 	}
 
@@ -387,7 +775,66 @@ func (ui *uiComponents) changeMenu(env *environment) error {
 }
 
 func handleMouseButtonEvents(event *sdl.MouseButtonEvent, env *environment) {
-	if ui.menuMode || ui.waitingFile {
+	if ui.menuMode {
+		handleMenuClick(event, env)
+		return
+	}
+
+	if ui.waitingFile {
+		return
+	}
+
+	// O botão do meio move a camera
+	if event.Button == sdl.BUTTON_MIDDLE {
+		if event.Type == sdl.MOUSEBUTTONDOWN {
+			ui.camera.startPan(env.w.toLogical(event.X, event.Y))
+		} else {
+			ui.camera.stopPan()
+		}
+
+		return
+	}
+
+	// A linha do tempo fica fora do diagrama
+	if event.Button == sdl.BUTTON_LEFT {
+		if event.Type == sdl.MOUSEBUTTONUP {
+			ui.timeline.release()
+		} else if ui.timeline.handleClick(env.w.toLogical(event.X, event.Y)) {
+			return
+		}
+	}
+
+	if ui.editor.active && ui.editor.handleMouse(event, env) {
+		return
+	}
+
+	// O lote e a arvore cobrem o diagrama e recebem todos os cliques
+	if ui.batch.active {
+		if event.Button == sdl.BUTTON_LEFT && event.Type == sdl.MOUSEBUTTONDOWN {
+			_, err := ui.batch.handleClick(env.w.toLogical(event.X, event.Y), env)
+			if err != nil {
+				env.throw(err)
+			}
+		}
+
+		return
+	}
+
+	if ui.tree.active {
+		if event.Button == sdl.BUTTON_LEFT && event.Type == sdl.MOUSEBUTTONDOWN {
+			ui.tree.handleClick(env.w.toLogical(event.X, event.Y), env)
+		}
+
+		return
+	}
+
+	// O botão direito marca os breakpoints
+	if event.Button == sdl.BUTTON_RIGHT {
+		if event.Type == sdl.MOUSEBUTTONDOWN && !ui.editor.active {
+			screen := env.w.toLogical(event.X, event.Y)
+			ui.breaks.handleClick(screen, ui.camera.toWorld(screen))
+		}
+
 		return
 	}
 
@@ -418,24 +865,118 @@ func handleMouseButtonEvents(event *sdl.MouseButtonEvent, env *environment) {
 
 	case sdl.MOUSEBUTTONUP:
 		if dragInfo.leftMouseDown {
+			moved := dragInfo.selected != nil && dragInfo.moved
 			dragInfo.leftMouseDown = false
 			dragInfo.selected = nil
+			dragInfo.moved = false
+
+			// O editor salva o layout junto com a maquina
+			// Na comparação as posições são sempre automaticas
+			if moved && !ui.editor.active && ui.compare == nil {
+				err := env.saveLayout()
+				if err != nil {
+					env.throw(err)
+				}
+			}
 		}
 	}
 }
 
 func handleMouseMotionEvent(env *environment) {
-	if ui.menuMode || ui.waitingFile {
+	x, y, buttons := sdl.GetMouseState()
+	screen := env.w.toLogical(x, y)
+
+	// O botão pode ter sido solto com um menu aberto
+	if buttons&sdl.ButtonLMask() == 0 {
+		ui.timeline.release()
+	}
+
+	// O mouse seleciona a opção sob ele
+	if ui.menuMode {
+		menu := ui.menuInfo.currentMenu
+		if index := menu.indexAt(screen); index > 0 {
+			menu.CurrentIndex = index
+		}
+
 		return
 	}
 
-	x, y, _ := sdl.GetMouseState()
+	if ui.waitingFile {
+		return
+	}
+
+	ui.camera.pan(screen)
+	ui.timeline.drag(screen)
+
+	// A posição do mouse é guardada no mundo, como a dos estados
 	dragInfo := ui.dragInfo
-	dragInfo.mousePos = &sdl.Point{X: x, Y: y}
+	mousePos := ui.camera.toWorld(screen)
+	dragInfo.mousePos = &mousePos
+	ui.tooltip.hover(env, mousePos, screen)
 	if dragInfo.leftMouseDown && dragInfo.selected != nil {
 		dragInfo.selected.X = dragInfo.mousePos.X - dragInfo.clickOffset.X
 		dragInfo.selected.Y = dragInfo.mousePos.Y - dragInfo.clickOffset.Y
+		dragInfo.moved = true
+	}
+}
+
+// Clique em uma opção escolhe a opção, como o enter. Fora do menu fecha os
+// menus.
+func handleMenuClick(event *sdl.MouseButtonEvent, env *environment) {
+	if event.Button != sdl.BUTTON_LEFT || event.Type != sdl.MOUSEBUTTONDOWN {
+		return
+	}
+
+	menu := ui.menuInfo.currentMenu
+	p := env.w.toLogical(event.X, event.Y)
+	if index := menu.indexAt(p); index > 0 {
+		menu.CurrentIndex = index
+		err := ui.changeMenu(env)
+		if err != nil {
+			env.throw(err)
+		}
+
+		return
+	}
+
+	// O campo de digitação da entrada não é uma lista
+	if menu.Name != "input" && !menu.contains(p) {
+		ui.closeMenus(env)
+	}
+}
+
+// Zoom centrado no mouse
+func handleMouseWheelEvent(event *sdl.MouseWheelEvent, env *environment) {
+	if ui.waitingFile || event.Y == 0 {
+		return
 	}
+
+	if ui.menuMode {
+		ui.menuInfo.currentMenu.scroll(-event.Y)
+		return
+	}
+
+	factor := ZOOM_STEP
+	if event.Y < 0 {
+		factor = 1 / ZOOM_STEP
+	}
+
+	x, y, _ := sdl.GetMouseState()
+	mouse := env.w.toLogical(x, y)
+
+	// Sobre o lote a roda rola as linhas
+	if ui.batch.active && mouse.InRect(&ui.batch.rect) {
+		ui.batch.scroll(-int(event.Y))
+		return
+	}
+
+	// Sobre a fita a roda rola as celulas
+	if mouse.InRect(&ui.tape.rect) {
+		ui.tape.scroll(-int(event.Y))
+		return
+	}
+
+	ui.camera.zoomAt(mouse, factor)
 }
 
 func draw(env *environment) {
@@ -451,11 +992,40 @@ func draw(env *environment) {
 		env.throw(err)
 	}
 
+	if ui.tree.active && !ui.editor.active {
+		err = ui.tree.draw(window)
+		if err != nil {
+			env.throw(err)
+		}
+	}
+
+	if ui.batch.active && !ui.editor.active {
+		err = ui.batch.draw(window)
+		if err != nil {
+			env.throw(err)
+		}
+	}
+
 	err = drawUi(env)
 	if err != nil {
 		env.throw(err)
 	}
 
+	if ui.tooltip.visible() {
+		err = ui.tooltip.draw(window)
+		if err != nil {
+			env.throw(err)
+		}
+	}
+
+	// O painel de erro fica por cima de tudo
+	if ui.errorPanel != nil {
+		err = ui.errorPanel.draw(window)
+		if err != nil {
+			fmt.Println(err)
+		}
+	}
+
 	window.renderer.Present()
 }
 
@@ -480,6 +1050,11 @@ func drawUi(env *environment) error {
 		return err
 	}
 
+	err = ui.timeline.draw(env.w, ui.tape.rect)
+	if err != nil {
+		return err
+	}
+
 	machineType := env.machine.Type()
 	if machineType != machine.SIMPLE_MACHINE {
 		err = ui.drawStacks(env.w)
@@ -494,13 +1069,33 @@ func drawUi(env *environment) error {
 		return err
 	}
 
+	// O editor usa o mesmo canto da tela que o aviso do alfabeto
+	if ui.editor.active {
+		err = ui.editor.draw(env.w)
+	} else {
+		err = ui.drawWarnings(env.w)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Erro da recarga automatica, até o arquivo ser corrigido
+	if env.watcher.err != nil {
+		err = env.watcher.err.drawBanner(env.w)
+		if err != nil {
+			return err
+		}
+	}
+
 	if ui.menuMode {
 		if ui.menuInfo.currentMenu == nil {
 			ui.menuInfo.currentMenu = ui.menuInfo.menus["main"]
 		}
 
-		ui.drawMenu(env.w)
+		err = ui.drawMenu(env.w)
 		if err != nil {
+			ui.closeMenus(env)
 			return err
 		}
 	}
@@ -522,32 +1117,76 @@ func (ui *uiComponents) update(env *environment) {
 		now := sdl.GetTicks64()
 		if now > fpsTimer+uint64(delayAnimation*1000) {
 			ui.nextComputation()
-			env.running = ui.indexComputation != (len(ui.bufferComputation.History) - 1)
+			env.running = ui.indexComputation != ui.lastIndex()
 			fpsTimer = now
+
+			// Na comparação a maquina carregada pode já ter parado
+			details := ui.bufferComputation.History[ui.step()].Details()
+			if message, ok := ui.breaks.hit(details); ok && ui.step() == ui.indexComputation {
+				ui.setStatus(message)
+				env.running = false
+			}
 		}
 	}
 
 	// Pinta todos os estados com a cor default
 	for _, state := range ui.states {
 		state.Color = COLOR_DEFAULT
+		state.result = ""
 	}
 
-	// Atualiza o buffer que printa a fita
-	ui.bufferInput = ajustBufferInput(env.machine.GetInput(), ui.indexComputation)
+	// Cada passo da computação consome um simbolo da fita
+	ui.tape.set(env.machine.GetInput().ToArray(), ui.indexComputation, ui.indexComputation)
+
+	// Durante a edição os estados da computação podem não existir mais
+	ui.fired = nil
+	if ui.editor.active {
+		ui.editor.colorStates()
+		return
+	}
 
 	// Historico da computação atual
-	record := ui.bufferComputation.History[ui.indexComputation]
+	record := ui.bufferComputation.History[ui.step()]
+	ui.fired = colorRecord(ui.states, record)
+	if ui.compare != nil {
+		ui.compare.update(env)
+	}
+}
+
+// Pinta o estado atual do registro e retorna a transição feita, nil no
+// passo inicial
+func colorRecord(states map[string]*graphicalState, record machine.ComputationRecord) *firedTransition {
 	details := record.Details()
 
 	// Cor do proximo estado
-	nextSate := ui.states[details["NEXT_STATE"]]
+	nextSate := states[details["NEXT_STATE"]]
 	switch details["RESULT"] {
 	case machine.INITIAL:
-		nextSate.Color = BLUE
+		nextSate.Color = COLOR_INITIAL
 	case machine.ACCEPTED:
-		nextSate.Color = GREEN
+		nextSate.Color = COLOR_ACCEPTED
+	case machine.REJECTED:
+		nextSate.Color = COLOR_REJECTED
 	default:
-		nextSate.Color = RED
+		nextSate.Color = COLOR_CURRENT
+	}
+
+	// O resultado também aparece como texto ao lado do estado, para não
+	// depender só da cor
+	nextSate.result = details["RESULT"]
+
+	// A aresta, o rotulo e a celula da fita da transição feita recebem a
+	// mesma cor do estado destino
+	if details["TRANSITION"] == "" {
+		return nil
+	}
+
+	return &firedTransition{
+		from:   details["LAST_STATE"],
+		to:     details["NEXT_STATE"],
+		label:  layout.LabelOf(details["TRANSITION"]),
+		symbol: details["SYMBOL"],
+		color:  nextSate.Color,
 	}
 }
 
@@ -560,73 +1199,107 @@ func (ui *uiComponents) init(env *environment, redraw bool) {
 	}
 
 	ui.closeMenus(env)
-	bufferInput := ajustBufferInput(env.input, 0)
-	computation := machine.Execute(env.machine, env.input)
 
-	if env.machine.Type() == machine.TWO_STACK_MACHINE {
-		machine, _ := env.machine.(*twoStackMachine.Machine)
-		ui.stackA, ui.stackB = machine.StackHistory()
-	} else if env.machine.Type() == machine.ONE_STACK_MACHINE {
-		machine, _ := env.machine.(*oneStackMachine.Machine)
-		ui.stackA = machine.StackHistory()
-		if ui.stackB != nil {
-			ui.stackB = nil
-		}
+	// O lote roda de novo na maquina nova
+	if redraw && ui.batch.rows != nil {
+		ui.batch.run(env)
 	}
 
+	computation := machine.Execute(env.machine, env.input)
+	ui.tree.clear()
+
 	if redraw {
 		ui.states = machineStates(env)
 		ui.dragInfo = dragInfo
+		ui.tooltip.clear()
+		ui.camera.reset()
 	}
 
 	ui.indexComputation = 0
 	ui.bufferComputation = *computation
-	ui.bufferInput = bufferInput
+	ui.tape.offset = 0
 	env.machine.GetInput().Reset()
 
+	if ui.compare != nil {
+		if redraw {
+			ui.compare.layout(env.w)
+		}
+
+		ui.compare.run(env)
+	}
+
 	initial := ui.bufferComputation.History[0]
 	initalDetails := initial.Details()
 	firstState := ui.states[initalDetails["LAST_STATE"]]
-	firstState.Color = BLUE
+	firstState.Color = COLOR_INITIAL
 
 	env.running = false
 }
 
 func (ui *uiComponents) reset(env *environment) {
-	bufferInput := ajustBufferInput(env.machine.GetInput(), 0)
-	ui.bufferInput = bufferInput
 	ui.indexComputation = 0
 	initial := ui.bufferComputation.History[0]
 	initalDetails := initial.Details()
 	firstState := ui.states[initalDetails["LAST_STATE"]]
-	firstState.Color = BLUE
+	firstState.Color = COLOR_INITIAL
 	env.running = false
+
+	// A animação volta a parar na divergencia
+	if ui.compare != nil {
+		ui.compare.stopped = false
+	}
 }
 
 func drawNodes(env *environment) error {
 	var err error
 
-	for _, state := range ui.states {
-		err = state.Draw(env.w, ui.states)
+	// Os estados são desenhados na posição da tela, segundo a camera
+	ui.breaks.labels = nil
+	states := ui.camera.view(ui.states)
+	for _, state := range states {
+		err = state.Draw(env.w, states)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ui.compare != nil && !ui.editor.active {
+		err = ui.compare.draw(env)
 		if err != nil {
 			return err
 		}
+
+		return ui.compare.drawPanel(env)
 	}
 
 	return nil
 }
 
-func ajustBufferInput(input *collections.Fita, index int) []string {
-	arrayInput := input.ToArray()
-	return utils.AjustMaxLen(arrayInput, index, TAMANHO_ESTRUTURAS)
+// Estados dos diagramas desenhados, incluindo os da comparação
+func (ui *uiComponents) diagramStates() map[string]*graphicalState {
+	if ui.compare == nil {
+		return ui.states
+	}
+
+	result := make(map[string]*graphicalState)
+	for name, state := range ui.states {
+		result["a:"+name] = state
+	}
+
+	for name, state := range ui.compare.states {
+		result["b:"+name] = state
+	}
+
+	return result
 }
 
 func (w *_SDLWindow) textSurface(text string, color sdl.Color) (*sdl.Surface, error) {
 	font := w.font
 	words := w.cacheWords
 
-	// Já tem no cache
-	surface := words[text]
+	// Já tem no cache. O mesmo texto pode aparecer em cores diferentes.
+	key := fmt.Sprintf("%s#%02x%02x%02x%02x", text, color.R, color.G, color.B, color.A)
+	surface := words[key]
 	if surface != nil {
 		return surface, nil
 	}
@@ -637,7 +1310,7 @@ func (w *_SDLWindow) textSurface(text string, color sdl.Color) (*sdl.Surface, er
 		return nil, err
 	}
 
-	words[text] = surface
+	words[key] = surface
 	return surface, nil
 }
 
@@ -648,26 +1321,6 @@ func Center(rec *sdl.Rect) sdl.Point {
 	}
 }
 
-func (st *stackHist) get(i int) ([]string, []string) {
-	var a []string
-	var b []string
-	if st.stackA != nil {
-		a = st.stackA[i]
-		if len(a) > TAMANHO_ESTRUTURAS {
-			a = a[len(a)-TAMANHO_ESTRUTURAS:]
-		}
-	}
-
-	if st.stackB != nil {
-		b = st.stackB[i]
-		if len(b) > TAMANHO_ESTRUTURAS {
-			b = b[len(b)-TAMANHO_ESTRUTURAS:]
-		}
-	}
-
-	return a, b
-}
-
 func (env *environment) Quit() {
 	env.terminate = true
 }
@@ -679,11 +1332,40 @@ func (ui *uiComponents) previusComputation() {
 }
 
 func (ui *uiComponents) nextComputation() {
-	if ui.indexComputation < len(ui.bufferComputation.History)-1 {
+	if ui.indexComputation < ui.lastIndex() {
 		ui.indexComputation++
 	}
 }
 
+// Pula para o passo index, limitado à computação
+func (ui *uiComponents) goToStep(index int) {
+	if index > ui.lastIndex() {
+		index = ui.lastIndex()
+	}
+
+	if index < 0 {
+		index = 0
+	}
+
+	ui.indexComputation = index
+}
+
+// Ultimo passo da animação. Na comparação vai até a maquina que parar por
+// ultimo.
+func (ui *uiComponents) lastIndex() int {
+	last := len(ui.bufferComputation.History) - 1
+	if ui.compare != nil && len(ui.compare.computation.History)-1 > last {
+		last = len(ui.compare.computation.History) - 1
+	}
+
+	return last
+}
+
+// Passo da computação da maquina carregada mostrado no passo atual
+func (ui *uiComponents) step() int {
+	return stepOf(&ui.bufferComputation, ui.indexComputation)
+}
+
 func (env *environment) stopTyping() {
 	env.typing = false
 }
@@ -697,16 +1379,88 @@ func (env *environment) startTyping() {
 	env.typing = true
 }
 
-func (env *environment) loadMachine(machine machine.Machine) {
-	env.machine = machine
-	env.input = machine.GetInput()
+func (env *environment) loadMachine(m machine.Machine) error {
+	// A entrada default também precisa respeitar o alfabeto
+	_, err := machine.CheckInput(m, m.GetInput(), env.alfabetMode)
+	if err != nil {
+		return err
+	}
+
+	env.machine = m
+	env.defaultInput = m.GetInput()
+	env.input = env.defaultInput
+	return nil
+}
+
+func (env *environment) setInput(input *collections.Fita) error {
+	// No modo leniente a entrada roda, mas os simbolos são reportados no
+	// canto da tela, a partir do historico da computação
+	_, err := machine.CheckInput(env.machine, input, env.alfabetMode)
+	if err != nil {
+		return err
+	}
+
+	env.input = input
+	return nil
+}
+
+func (env *environment) toggleAlfabetMode() {
+	if env.alfabetMode == machine.STRICT_ALFABET {
+		env.alfabetMode = machine.LENIENT_ALFABET
+		ui.setStatus("Alfabeto: modo leniente")
+	} else {
+		env.alfabetMode = machine.STRICT_ALFABET
+		ui.setStatus("Alfabeto: modo estrito")
+	}
+
+	// No modo estrito algumas linhas do lote deixam de rodar
+	if ui.batch.rows != nil {
+		ui.batch.run(env)
+	}
+}
+
+// Salva a posição dos estados no arquivo da maquina carregada
+func (env *environment) saveLayout() error {
+	if env.machinePath == "" {
+		return nil
+	}
+
+	err := reader.WriteLayout(env.machinePath, statesLayout(ui.states))
+	if err != nil {
+		return err
+	}
+
+	// Não recarrega a maquina por causa da propria escrita
+	env.watchFiles()
+	return nil
 }
 
 func (env *environment) saveInput() error {
-	return reader.WriteInput(env.input, INPUT_PATH)
+	return reader.WriteInput(env.input, config.InputsDir)
 }
 
+// Mostra o erro na tela sem encerrar o simulador. A maquina e a entrada
+// atuais continuam carregadas.
 func (env *environment) throw(err error) {
-	fmt.Println(err)
-	env.Quit()
+	panel := &errorPanel{message: err.Error()}
+	var fe *fileError
+	if errors.As(err, &fe) {
+		panel.message = fe.err.Error()
+		panel.path = fe.path
+	}
+
+	// Erros de desenho se repetem a cada frame
+	if ui.errorPanel == nil || *ui.errorPanel != *panel {
+		fmt.Println(err)
+	}
+
+	ui.errorPanel = panel
+}
+
+func (e *fileError) Error() string {
+	return fmt.Sprintf("%s: %s", e.path, e.err)
+}
+
+func (e *fileError) Unwrap() error {
+	return e.err
 }
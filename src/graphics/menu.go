@@ -0,0 +1,180 @@
+package graphics
+
+import (
+	"autosimulator/src/reader"
+	"path/filepath"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Navegação de arquivos dos menus de maquinas e de entradas. A lista mostra
+// os subdiretorios (terminados em /) antes dos arquivos e ".." fora do
+// diretorio raiz. O que é digitado com o menu aberto filtra a lista.
+
+const (
+	PARENT_DIR = ".."
+)
+
+type fileBrowser struct {
+	// Extensão dos arquivos listados. Define também o diretorio raiz.
+	ext string
+
+	// Subdiretorio atual, relativo à raiz
+	dir    string
+	filter string
+}
+
+func (fb *fileBrowser) root() string {
+	if fb.ext == ".json" {
+		return config.MachinesDir
+	}
+
+	return config.InputsDir
+}
+
+func (fb *fileBrowser) options() ([]string, error) {
+	dirs, files, err := reader.GetDirList(filepath.Join(fb.root(), fb.dir), fb.ext)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []string{}
+	if fb.dir != "" {
+		options = append(options, PARENT_DIR)
+	}
+
+	filter := strings.ToLower(fb.filter)
+	for _, dir := range dirs {
+		if strings.Contains(strings.ToLower(dir), filter) {
+			options = append(options, dir+"/")
+		}
+	}
+
+	for _, file := range files {
+		if strings.Contains(strings.ToLower(file), filter) {
+			options = append(options, file)
+		}
+	}
+
+	return options, nil
+}
+
+// Abre a opção escolhida. Retorna o caminho do arquivo ou false quando a
+// opção era um diretorio.
+func (fb *fileBrowser) open(option string) (string, bool) {
+	switch {
+	case option == PARENT_DIR:
+		fb.dir = filepath.Dir(fb.dir)
+		if fb.dir == "." {
+			fb.dir = ""
+		}
+
+	case strings.HasSuffix(option, "/"):
+		fb.dir = filepath.Join(fb.dir, strings.TrimSuffix(option, "/"))
+
+	default:
+		return filepath.Join(fb.root(), fb.dir, option), true
+	}
+
+	fb.filter = ""
+	return "", false
+}
+
+// Apaga o ultimo caractere do filtro. Com o filtro vazio volta um diretorio.
+func (fb *fileBrowser) backspace() {
+	if fb.filter == "" {
+		if fb.dir != "" {
+			fb.open(PARENT_DIR)
+		}
+
+		return
+	}
+
+	runes := []rune(fb.filter)
+	fb.filter = string(runes[:len(runes)-1])
+}
+
+// Opção selecionada. false quando a lista está vazia.
+func (sb *SelectBox) Selected() (string, bool) {
+	if sb.CurrentIndex < 1 || int(sb.CurrentIndex) > len(sb.Options) {
+		return "", false
+	}
+
+	return sb.Options[sb.CurrentIndex-1], true
+}
+
+// Escolhe a opção selecionada de um menu de arquivos. Diretorios são
+// abertos no proprio menu e retornam false.
+func (sb *SelectBox) pick() (string, bool) {
+	option, ok := sb.Selected()
+	if !ok {
+		return "", false
+	}
+
+	path, ok := sb.Files.open(option)
+	sb.reload()
+	return path, ok
+}
+
+// A lista é lida de novo no proximo desenho
+func (sb *SelectBox) reload() {
+	sb.Options = nil
+	sb.CurrentIndex = 1
+	sb.Offset = 0
+}
+
+func (sb *SelectBox) typeFilter(text string) {
+	sb.Files.filter += text
+	sb.reload()
+}
+
+func (sb *SelectBox) scroll(amount int32) {
+	sb.Offset += amount
+	if sb.Offset > int32(len(sb.Options))-sb.MaxItems {
+		sb.Offset = int32(len(sb.Options)) - sb.MaxItems
+	}
+
+	if sb.Offset < 0 {
+		sb.Offset = 0
+	}
+
+	// A seleção acompanha a parte visivel
+	if sb.CurrentIndex <= sb.Offset {
+		sb.CurrentIndex = sb.Offset + 1
+	}
+
+	if sb.CurrentIndex > sb.Offset+sb.MaxItems {
+		sb.CurrentIndex = sb.Offset + sb.MaxItems
+	}
+}
+
+// Opção (começando em 1) sob o ponto p. 0 fora da lista.
+func (sb *SelectBox) indexAt(p sdl.Point) int32 {
+	if sb.Rect == nil || p.X < sb.X || p.X >= sb.X+sb.W || p.Y < sb.Y {
+		return 0
+	}
+
+	row := (p.Y - sb.Y) / sb.H
+	index := sb.Offset + row + 1
+	if row >= sb.MaxItems || int(index) > len(sb.Options) {
+		return 0
+	}
+
+	return index
+}
+
+// Area ocupada pela lista, incluindo o filtro dos menus de arquivos
+func (sb *SelectBox) contains(p sdl.Point) bool {
+	if sb.Rect == nil {
+		return false
+	}
+
+	area := sdl.Rect{X: sb.X, Y: sb.Y, W: sb.W, H: sb.H * sb.MaxItems}
+	if sb.Files != nil {
+		area.Y -= sb.H + PADY*2
+		area.H += sb.H + PADY*2
+	}
+
+	return p.InRect(&area)
+}
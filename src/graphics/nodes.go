@@ -1,10 +1,10 @@
 package graphics
 
 import (
+	"autosimulator/src/layout"
 	"autosimulator/src/machine"
+	"autosimulator/src/utils"
 	"errors"
-	"fmt"
-	"math/rand"
 
 	"github.com/veandco/go-sdl2/gfx"
 	"github.com/veandco/go-sdl2/sdl"
@@ -18,6 +18,16 @@ type (
 
 		// As keys para os estados que este aponta
 		statesKeys []string
+
+		// Rotulos das transições, agrupados pelo estado destino
+		labels map[string][]string
+
+		initial bool
+		final   bool
+
+		// Resultado do passo atual ([I], [V] ou [X]) quando este é o estado
+		// atual
+		result string
 	}
 )
 
@@ -25,8 +35,11 @@ const (
 	// Constantes para desenhar os estados
 	WIDTH_REC  = 50
 	HEIGTH_REC = 50
-	X_FIRST    = 10
-	Y_FIRST    = 10
+
+	// Constantes para desenhar as transições
+	CURVE_SEGMENTS = 16
+	ARROW_SIZE     = DIMENSAO_ESTRUTURAS / 3
+	LABEL_SCALE    = 0.6
 )
 
 func NewState(rect *sdl.Rect, state string, color sdl.Color, statesKeys []string) *graphicalState {
@@ -54,9 +67,37 @@ func (s *graphicalState) Draw(w *_SDLWindow, states map[string]*graphicalState)
 		return err
 	}
 
+	// Estados finais recebem um segundo anel
+	if s.final {
+		err = s.drawRing(renderer, outerRadius-PADX, innerRadius-PADX, s.Color)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = ui.breaks.drawState(renderer, s)
+	if err != nil {
+		return err
+	}
+
+	// Seta de entrada no estado inicial
+	if s.initial {
+		center := s.Center()
+		headBase := s.H / 4
+		err = drawArrowRight(renderer, s.X, center.Y, headBase, headBase/2, s.Color)
+		if err != nil {
+			return err
+		}
+
+		ok := gfx.ThickLineColor(renderer, s.X-s.W/2, center.Y, s.X, center.Y, 2, s.Color)
+		if !ok {
+			return errors.New("erro ao renderizar a seta do estado inicial")
+		}
+	}
+
 	var lineThickness int32 = 2
 	if len(s.statesKeys) != 0 {
-		err = s.drawLines(renderer, states, lineThickness)
+		err = s.drawLines(w, states, lineThickness)
 		if err != nil {
 			return err
 		}
@@ -67,6 +108,15 @@ func (s *graphicalState) Draw(w *_SDLWindow, states map[string]*graphicalState)
 		return err
 	}
 
+	// O resultado vai abaixo do estado, já que os laços ficam acima
+	if s.result != "" && s.result != machine.RUNNING {
+		center := s.Center()
+		err = drawTextColor(w, s.result, sdl.Point{X: center.X, Y: s.Y + s.H + DIMENSAO_ESTRUTURAS/3}, s.Color)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -153,10 +203,9 @@ func (s *graphicalState) drawText(window *_SDLWindow, words map[string]*sdl.Surf
 	if err != nil {
 		return err
 	}
-	_, _, fontW, fontH, err := texture.Query()
-	if err != nil {
-		return err
-	}
+
+	defer texture.Destroy()
+	fontW, fontH := window.textSize(surface)
 
 	centerS := s.Center()
 	textRect := &sdl.Rect{
@@ -170,13 +219,15 @@ func (s *graphicalState) drawText(window *_SDLWindow, words map[string]*sdl.Surf
 	return nil
 }
 
-func (s *graphicalState) drawLines(renderer *sdl.Renderer, states map[string]*graphicalState, thickness int32) error {
-	// Desenha os estados cujo o estado atual aponta
+func (s *graphicalState) drawLines(w *_SDLWindow, states map[string]*graphicalState, thickness int32) error {
+	// Desenha uma aresta para cada estado que o estado atual aponta
 	var err error
+	drawn := make(map[string]bool)
 	for _, next := range s.statesKeys {
 		state := states[next]
-		if state != nil {
-			err = s.drawLine(renderer, state, thickness)
+		if state != nil && !drawn[next] {
+			drawn[next] = true
+			err = s.drawLine(w, state, thickness)
 			if err != nil {
 				return err
 			}
@@ -186,92 +237,191 @@ func (s *graphicalState) drawLines(renderer *sdl.Renderer, states map[string]*gr
 	return nil
 }
 
-// Função que desenha uma linah entre dois estados. O estato "To" recebera a bolinha (cardinalidade)!!
-func (from *graphicalState) drawLine(renderer *sdl.Renderer, to *graphicalState, thickness int32) error {
-	fromCenter := from.Center()
-	toCenter := to.Center()
+// Desenha a aresta entre dois estados, com a ponta de seta no estado "to" e
+// os rotulos de todas as transições entre eles
+func (from *graphicalState) drawLine(w *_SDLWindow, to *graphicalState, thickness int32) error {
+	renderer := w.renderer
 	radius := float64(from.H / 2)
-	radiusMiniBall := thickness * 2
-
-	record := ui.bufferComputation.History[ui.indexComputation]
-	details := record.Details()
-	previus := ui.states[details["LAST_STATE"]]
 
+	// A aresta da transição feita é destacada com a cor do estado destino
 	color := COLOR_DEFAULT
-	if previus == from && machine.INITIAL != details["RESULT"] {
-		color = to.Color
+	highlight := ""
+	fired := ui.fired
+	if fired != nil && fired.from == from.state && fired.to == to.state {
+		color = fired.color
+		highlight = fired.label
+		thickness *= 2
+	}
+
+	fromCenter := centerPosition(from.Rect)
+	var curve layout.Curve
+	switch {
+	case from == to:
+		curve = layout.SelfLoop(fromCenter, radius)
+	case len(to.labels[from.state]) > 0:
+		// Arestas nos dois sentidos são curvadas para não se sobreporem
+		curve = layout.EdgeCurve(fromCenter, centerPosition(to.Rect), radius, radius)
+	default:
+		curve = layout.EdgeCurve(fromCenter, centerPosition(to.Rect), radius, 0)
 	}
 
-	if from == to {
-		var start int32 = 250
-		var end int32 = 100
-		gfx.ArcRGBA(renderer,
-			fromCenter.X+int32(radius),
-			fromCenter.Y,
-			int32(radius*0.7),
-			start,
-			end,
-			color.R,
-			color.G,
-			color.B,
-			color.A,
-		)
-
-		//Desenha o marcador de cardinalidade no final da linha
-		ok := gfx.FilledCircleColor(renderer, fromCenter.X+int32(radius), fromCenter.Y+int32(radius*0.7), radiusMiniBall, color)
+	points := curve.Points(CURVE_SEGMENTS)
+	for i := 1; i < len(points); i++ {
+		ok := gfx.ThickLineColor(renderer, points[i-1].X, points[i-1].Y, points[i].X, points[i].Y, thickness, color)
 		if !ok {
 			return errors.New("erro ao renderizar as linhas")
 		}
+	}
 
-	} else {
-		// Calcula o ponto inicial e final da linha
-		start, end, ok := LinePoints(fromCenter, toCenter, radius, radius+float64(radiusMiniBall))
-		if !ok {
-			return nil
+	tip, left, right := curve.ArrowHead(ARROW_SIZE * ui.camera.zoom)
+	ok := gfx.FilledTrigonColor(renderer, tip.X, tip.Y, left.X, left.Y, right.X, right.Y, color)
+	if !ok {
+		return errors.New("erro ao renderizar as linhas")
+	}
+
+	return drawLabels(w, curve, from.labels[to.state], to.state, highlight, color)
+}
+
+// Desenha os rotulos das transições para o estado to ao lado da aresta, um
+// por linha e em uma fonte menor que a dos estados. O rotulo highlight (a
+// transição feita) é desenhado na cor color e dentro de uma caixa.
+func drawLabels(w *_SDLWindow, curve layout.Curve, labels []string, to, highlight string, color sdl.Color) error {
+	if len(labels) == 0 {
+		return nil
+	}
+
+	scale := LABEL_SCALE * ui.camera.zoom
+	lineHeight := int32(float64(w.font.Height()) / w.scale * scale)
+	lines, align := curve.LabelLines(float64(PADX)*ui.camera.zoom, len(labels), lineHeight)
+	for i, label := range labels {
+		labelColor := COLOR_DEFAULT
+		if label == highlight {
+			labelColor = color
 		}
 
-		if start.X < 0 {
-			fmt.Println(fromCenter, toCenter, radius, radius+float64(radiusMiniBall))
-			fmt.Println(start, end)
+		surface, err := w.textSurface(label, labelColor)
+		if err != nil {
+			return err
 		}
 
-		// Desenha a linha
-		ok = gfx.ThickLineColor(renderer, start.X, start.Y, end.X, end.Y, thickness, color)
-		if !ok {
-			return errors.New("erro ao renderizar as linhas")
+		texture, err := w.renderer.CreateTextureFromSurface(surface)
+		if err != nil {
+			return err
 		}
 
-		// Desenha o marcador de cardinalidade no final da linha
-		ok = gfx.FilledCircleColor(renderer, end.X, end.Y, radiusMiniBall, color)
-		if !ok {
-			return errors.New("erro ao renderizar as linhas")
+		textW, textH := w.textSize(surface)
+		width := int32(float64(textW) * scale)
+		height := int32(float64(textH) * scale)
+		x := lines[i].X - width/2
+		switch align {
+		case layout.ALIGN_START:
+			x = lines[i].X
+		case layout.ALIGN_END:
+			x = lines[i].X - width
+		}
+
+		rect := sdl.Rect{X: x, Y: lines[i].Y - height/2, W: width, H: height}
+		if label == highlight {
+			box := sdl.Rect{X: rect.X - 2, Y: rect.Y, W: rect.W + 2, H: rect.H - 2}
+			err = drawRect(w.renderer, 1, box, labelColor, COLOR_BACKGROUD)
+			if err != nil {
+				texture.Destroy()
+				return err
+			}
+		}
+
+		w.renderer.Copy(texture, nil, &rect)
+		texture.Destroy()
+
+		err = ui.breaks.drawLabel(w.renderer, rect, joinTransition(label, to))
+		if err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
+func centerPosition(rect *sdl.Rect) machine.Position {
+	center := Center(rect)
+	return machine.Position{X: center.X, Y: center.Y}
+}
+
 func machineStates(env *environment) map[string]*graphicalState {
-	machine := env.machine
-	window := env.w
-	result := make(map[string]*graphicalState)
-	for _, state := range machine.GetStates() {
-		rect := &sdl.Rect{
-			// X: X_FIRST,
-			// Y: Y_FIRST + int32(i*HEIGTH_REC),
-			X: WIDTH_REC + rand.Int31n(window.WIDTH-WIDTH_REC*2),
-			Y: rand.Int31n(window.HEIGHT / 2),
-			W: WIDTH_REC,
-			H: HEIGTH_REC,
+	// Na comparação a maquina carregada ocupa a metade esquerda, sempre com
+	// o layout automatico
+	if ui.compare != nil {
+		left, _ := splitBounds(env.w)
+		return statesOf(env.machine, left, false)
+	}
+
+	return statesOf(env.machine, diagramBounds(env.w), true)
+}
+
+// Estados da maquina dentro de bounds. Com saved, a posição salva no
+// arquivo da maquina tem preferencia sobre o layout automatico.
+func statesOf(machine machine.Machine, bounds layout.Bounds, saved bool) map[string]*graphicalState {
+	edges := layout.Edges(machine)
+	labels := layout.Labels(machine)
+
+	positions := layout.Layered(machine.GetStates(), machine.GetInitialState(), edges, bounds, WIDTH_REC)
+	if saved {
+		for state, position := range machine.GetLayout() {
+			positions[state] = position
 		}
+	}
 
+	result := make(map[string]*graphicalState)
+	for _, state := range machine.GetStates() {
 		statesKeys := make([]string, 0)
-		for _, transition := range machine.GetTransitions(state) {
-			statesKeys = append(statesKeys, transition.GetResultState())
-		}
+		statesKeys = append(statesKeys, edges[state]...)
 
-		result[state] = NewState(rect, state, COLOR_DEFAULT, statesKeys)
+		result[state] = NewState(rectAt(positions[state]), state, COLOR_DEFAULT, statesKeys)
+		result[state].initial = state == machine.GetInitialState()
+		result[state].final = utils.Contains(machine.GetFinalStates(), state)
+		result[state].labels = labels[state]
 	}
 
 	return result
 }
+
+// Area da janela livre dos paineis da fita, pilhas e historico
+func diagramBounds(window *_SDLWindow) layout.Bounds {
+	return layout.Bounds{
+		X: PADX,
+		Y: PADY + HEIGTH_REC/2,
+		W: window.WIDTH - PADX*2,
+		H: window.HEIGHT - (DIMENSAO_ESTRUTURAS*TAMANHO_ESTRUTURAS + PADY*2) - HEIGTH_REC/2,
+	}
+}
+
+// Retangulo do estado com o centro em position
+func rectAt(position machine.Position) *sdl.Rect {
+	return &sdl.Rect{
+		X: position.X - WIDTH_REC/2,
+		Y: position.Y - HEIGTH_REC/2,
+		W: WIDTH_REC,
+		H: HEIGTH_REC,
+	}
+}
+
+// Posição do centro de cada estado, no formato salvo no JSON
+func statesLayout(states map[string]*graphicalState) map[string]machine.Position {
+	layout := make(map[string]machine.Position)
+	for name, state := range states {
+		center := state.Center()
+		layout[name] = machine.Position{X: center.X, Y: center.Y}
+	}
+
+	return layout
+}
+
+// Copia a posição dos estados que existem nos dois mapas
+func keepPositions(states, old map[string]*graphicalState) {
+	for name, state := range states {
+		if previus, ok := old[name]; ok {
+			state.X = previus.X
+			state.Y = previus.Y
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package graphics
+
+import (
+	"autosimulator/src/reader"
+	"os"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Recarga automatica da maquina e da entrada carregadas quando os arquivos
+// são alterados fora do simulador. A data de modificação dos arquivos é
+// verificada a cada RELOAD_INTERVAL milisegundos.
+
+const (
+	RELOAD_INTERVAL = 500
+)
+
+type watcher struct {
+	lastCheck   uint64
+	machineTime time.Time
+	inputTime   time.Time
+
+	// Erro da ultima recarga, mostrado até o arquivo ser corrigido
+	err *errorPanel
+}
+
+// Guarda a data de modificação atual dos arquivos carregados. Deve ser
+// chamada depois que o proprio simulador carrega ou escreve os arquivos.
+func (env *environment) watchFiles() {
+	env.watcher.machineTime = modTime(env.machinePath)
+	env.watcher.inputTime = modTime(env.inputPath)
+	env.watcher.err = nil
+}
+
+func (env *environment) checkReload() {
+	now := sdl.GetTicks64()
+	if now < env.watcher.lastCheck+RELOAD_INTERVAL {
+		return
+	}
+
+	env.watcher.lastCheck = now
+
+	// Durante a edição a maquina do editor tem preferencia
+	if ui.editor.active {
+		return
+	}
+
+	if t := modTime(env.machinePath); !t.Equal(env.watcher.machineTime) {
+		env.watcher.machineTime = t
+		env.reloadMachine()
+	}
+
+	if t := modTime(env.inputPath); !t.Equal(env.watcher.inputTime) {
+		env.watcher.inputTime = t
+		env.reloadInput()
+	}
+}
+
+// Relê a maquina mantendo a posição dos estados que continuam existindo, o
+// zoom da camera e a entrada atual (a não ser que seja a entrada default,
+// que também pode ter mudado)
+func (env *environment) reloadMachine() {
+	m, err := reader.ReadMachine(env.machinePath)
+	if err != nil {
+		env.reloadFailed(env.machinePath, err)
+		return
+	}
+
+	previusInput := env.input
+	defaultInput := env.input == env.defaultInput
+	err = env.loadMachine(m)
+	if err != nil {
+		env.reloadFailed(env.machinePath, err)
+		return
+	}
+
+	if !defaultInput {
+		err = env.setInput(previusInput)
+		if err != nil {
+			env.reloadFailed(env.machinePath, err)
+		}
+	}
+
+	old := ui.states
+	camera := *ui.camera
+	ui.init(env, true)
+	keepPositions(ui.states, old)
+	*ui.camera = camera
+
+	if err == nil {
+		env.watcher.err = nil
+	}
+}
+
+func (env *environment) reloadInput() {
+	input, err := reader.ReadInput(env.inputPath)
+	if err == nil {
+		err = env.setInput(input)
+	}
+
+	if err != nil {
+		env.reloadFailed(env.inputPath, err)
+		return
+	}
+
+	ui.init(env, false)
+	env.watcher.err = nil
+}
+
+func (env *environment) reloadFailed(path string, err error) {
+	env.watcher.err = &errorPanel{message: err.Error(), path: path}
+}
+
+// Data de modificação do arquivo. Zero se não houver arquivo.
+func modTime(path string) time.Time {
+	if path == "" {
+		return time.Time{}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return info.ModTime()
+}
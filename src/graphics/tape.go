@@ -0,0 +1,160 @@
+package graphics
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Painel da fita. Mostra a entrada inteira, TAMANHO_ESTRUTURAS celulas por
+// vez, com as celulas já consumidas em cinza e a cabeça sobre a celula
+// head. A janela visivel acompanha a cabeça e pode ser rolada com a roda do
+// mouse. A cabeça não depende das celulas consumidas, para servir também a
+// fitas de leitura e escrita.
+
+type tapePanel struct {
+	cells []string
+
+	// Celula sob a cabeça. Pode passar do fim da entrada (celulas vazias).
+	head int
+
+	// Quantidade de celulas consumidas, a partir do inicio da fita
+	consumed int
+
+	// Primeira celula visivel
+	offset int
+
+	// Area do ultimo desenho, para a roda do mouse
+	rect sdl.Rect
+}
+
+// Atualiza o conteudo da fita. A janela visivel só acompanha a cabeça
+// quando ela se move, para não desfazer a rolagem do usuario.
+func (tape *tapePanel) set(cells []string, head, consumed int) {
+	moved := head != tape.head || len(cells) != len(tape.cells)
+	tape.cells = cells
+	tape.head = head
+	tape.consumed = consumed
+	if moved {
+		tape.follow()
+	}
+}
+
+// Mantem a cabeça visivel, com uma celula antes dela para o simbolo
+// consumido pela ultima transição
+func (tape *tapePanel) follow() {
+	if tape.head-1 < tape.offset {
+		tape.offset = tape.head - 1
+	}
+
+	if tape.head > tape.offset+TAMANHO_ESTRUTURAS-2 {
+		tape.offset = tape.head - (TAMANHO_ESTRUTURAS - 2)
+	}
+
+	tape.clampOffset()
+}
+
+func (tape *tapePanel) scroll(amount int) {
+	tape.offset += amount
+	tape.clampOffset()
+}
+
+func (tape *tapePanel) clampOffset() {
+	last := len(tape.cells)
+	if tape.head >= last {
+		last = tape.head + 1
+	}
+
+	if tape.offset > last-TAMANHO_ESTRUTURAS {
+		tape.offset = last - TAMANHO_ESTRUTURAS
+	}
+
+	if tape.offset < 0 {
+		tape.offset = 0
+	}
+}
+
+// fired é a transição feita no passo atual. O simbolo que ela consumiu
+// (a celula antes da cabeça) recebe a borda na cor do estado destino.
+func (tape *tapePanel) draw(window *_SDLWindow, x, y int32, fired *firedTransition) error {
+	var cellWidth int32 = DIMENSAO_ESTRUTURAS
+	tape.rect = sdl.Rect{X: x, Y: y, W: cellWidth * TAMANHO_ESTRUTURAS, H: cellWidth}
+
+	for i := 0; i < TAMANHO_ESTRUTURAS; i++ {
+		index := tape.offset + i
+		cell := sdl.Rect{X: x + cellWidth*int32(i), Y: y, W: cellWidth, H: cellWidth}
+
+		color := COLOR_DEFAULT
+		if index < tape.consumed {
+			color = COLOR_CONSUMED
+		}
+
+		var thickness int32 = 1
+		border := color
+		if fired != nil && index == tape.head-1 {
+			thickness = 3
+			border = fired.color
+		}
+
+		err := drawRect(window.renderer, thickness, cell, border, COLOR_BACKGROUD)
+		if err != nil {
+			return err
+		}
+
+		if index < len(tape.cells) {
+			err = drawTextColor(window, tape.cells[index], Center(&cell), color)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Cabeça da fita
+	if tape.head >= tape.offset && tape.head < tape.offset+TAMANHO_ESTRUTURAS {
+		headX := x + cellWidth*int32(tape.head-tape.offset) + cellWidth/2
+		headBase := cellWidth / 2
+		err := drawArrowDown(window.renderer, headX, y-PADY, headBase, headBase/2, COLOR_DEFAULT)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Indica, acima das celulas das pontas, que há mais celulas fora da
+	// janela
+	if tape.offset > 0 {
+		color := COLOR_DEFAULT
+		if tape.offset <= tape.consumed {
+			color = COLOR_CONSUMED
+		}
+
+		err := drawTextColor(window, "<", sdl.Point{X: x + cellWidth/2, Y: y - cellWidth/2}, color)
+		if err != nil {
+			return err
+		}
+	}
+
+	if tape.offset+TAMANHO_ESTRUTURAS < len(tape.cells) {
+		err := drawTextColor(window, ">", sdl.Point{X: x + tape.rect.W - cellWidth/2, Y: y - cellWidth/2}, COLOR_DEFAULT)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Desenha um texto curto centralizado em center
+func drawTextColor(window *_SDLWindow, text string, center sdl.Point, color sdl.Color) error {
+	surface, err := window.textSurface(text, color)
+	if err != nil {
+		return err
+	}
+
+	texture, err := window.renderer.CreateTextureFromSurface(surface)
+	if err != nil {
+		return err
+	}
+
+	defer texture.Destroy()
+	w, h := window.textSize(surface)
+	rect := &sdl.Rect{X: center.X - w/2, Y: center.Y - h/2, W: w, H: h}
+	return window.renderer.Copy(texture, nil, rect)
+}
@@ -0,0 +1,78 @@
+package graphics
+
+import (
+	"autosimulator/src/theme"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Aplica os temas de cores (src/theme) às cores globais da interface. A
+// tecla k alterna entre os temas embutidos.
+
+// Tema embutido atual, para a tecla k
+var currentTheme = theme.THEME_DARK
+
+// Aplica o tema da configuração. Se ele não puder ser carregado fica o
+// tema escuro e o erro é retornado, para ser mostrado na janela.
+func initTheme(name string) error {
+	// Com um arquivo a tecla k continua a partir do tema escuro
+	currentTheme = theme.THEME_DARK
+	loaded, err := theme.Load(name)
+	if err != nil {
+		dark, _ := theme.Load(theme.THEME_DARK)
+		applyTheme(dark)
+		return err
+	}
+
+	if theme.IsBuiltin(name) {
+		currentTheme = name
+	}
+
+	return applyTheme(loaded)
+}
+
+// Troca as cores globais pelas do tema
+func applyTheme(t theme.Theme) error {
+	rgb, err := t.Colors()
+	if err != nil {
+		return err
+	}
+
+	colors := make([]sdl.Color, len(rgb))
+	for i, c := range rgb {
+		colors[i] = sdl.Color{R: c.R, G: c.G, B: c.B, A: 255}
+	}
+
+	COLOR_BACKGROUD = colors[0]
+	COLOR_DEFAULT = colors[1]
+	COLOR_INITIAL = colors[2]
+	COLOR_CURRENT = colors[3]
+	COLOR_ACCEPTED = colors[4]
+	COLOR_REJECTED = colors[5]
+	COLOR_WARNING = colors[6]
+	COLOR_ERROR = colors[7]
+	COLOR_CONSUMED = colors[8]
+	COLOR_HIGHLIGHT = colors[9]
+	return nil
+}
+
+// Proximo tema embutido, na ordem de theme.THEME_NAMES
+func nextTheme() error {
+	names := theme.THEME_NAMES
+	next := names[0]
+	for i, name := range names {
+		if name == currentTheme && i+1 < len(names) {
+			next = names[i+1]
+		}
+	}
+
+	t, _ := theme.Load(next)
+	err := applyTheme(t)
+	if err != nil {
+		return err
+	}
+
+	currentTheme = next
+	ui.setStatus("Tema: " + next)
+	return nil
+}
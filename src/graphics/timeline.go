@@ -0,0 +1,107 @@
+package graphics
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Linha do tempo da computação, acima da fita: mostra o passo atual, o
+// total de passos e a velocidade da animação. Clicar ou arrastar na barra
+// pula para o passo correspondente.
+
+const (
+	TIMELINE_HEIGHT = DIMENSAO_ESTRUTURAS / 3
+
+	// Passos pulados por PageUp e PageDown
+	TIMELINE_SKIP = 10
+
+	// Limites do tempo de cada passo da animação, em segundos
+	DELAY_MIN = 0.1
+	DELAY_MAX = 5.0
+)
+
+type timeline struct {
+	// Barra no ultimo desenho, para o clique
+	rect sdl.Rect
+
+	// O botão foi apertado na barra e ainda não foi solto
+	scrubbing bool
+}
+
+// Desenha a barra acima da fita, com a mesma largura dela
+func (tl *timeline) draw(window *_SDLWindow, tape sdl.Rect) error {
+	tl.rect = sdl.Rect{
+		X: tape.X,
+		Y: tape.Y - DIMENSAO_ESTRUTURAS*2,
+		W: tape.W,
+		H: TIMELINE_HEIGHT,
+	}
+
+	err := drawRect(window.renderer, 1, tl.rect, COLOR_DEFAULT, COLOR_BACKGROUD)
+	if err != nil {
+		return err
+	}
+
+	// Parte já percorrida da computação
+	last := ui.lastIndex()
+	if last > 0 && ui.indexComputation > 0 {
+		done := tl.rect
+		done.W = tl.rect.W * int32(ui.indexComputation) / int32(last)
+		err = drawRect(window.renderer, 1, done, COLOR_DEFAULT, COLOR_DEFAULT)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Marcador do passo atual
+	x := tl.rect.X
+	if last > 0 {
+		x += tl.rect.W * int32(ui.indexComputation) / int32(last)
+	}
+
+	marker := sdl.Rect{X: x - PADX/2, Y: tl.rect.Y - PADY, W: PADX, H: tl.rect.H + PADY*2}
+	err = drawRect(window.renderer, 1, marker, COLOR_HIGHLIGHT, COLOR_HIGHLIGHT)
+	if err != nil {
+		return err
+	}
+
+	text := fmt.Sprintf("passo %d/%d  %.1fs/passo", ui.indexComputation, last, delayAnimation)
+	maxLen := int(tl.rect.W / (DIMENSAO_ESTRUTURAS / 2))
+	return drawText(window, []string{text}, 0, tl.rect.X, tl.rect.Y-DIMENSAO_ESTRUTURAS/2, maxLen, TEXT_DOWN_LEFT)
+}
+
+// Começa a arrastar se o clique foi na barra. A area do clique é um pouco
+// mais alta que a barra.
+func (tl *timeline) handleClick(p sdl.Point) bool {
+	area := tl.rect
+	area.Y -= PADY * 2
+	area.H += PADY * 4
+	if !p.InRect(&area) {
+		return false
+	}
+
+	tl.scrubbing = true
+	tl.drag(p)
+	return true
+}
+
+func (tl *timeline) drag(p sdl.Point) {
+	if !tl.scrubbing || tl.rect.W == 0 {
+		return
+	}
+
+	position := float64(p.X-tl.rect.X) / float64(tl.rect.W)
+	ui.goToStep(int(math.Round(position * float64(ui.lastIndex()))))
+}
+
+func (tl *timeline) release() {
+	tl.scrubbing = false
+}
+
+// Muda o tempo de cada passo da animação, dentro dos limites
+func changeDelay(amount float64) {
+	delay := math.Round((delayAnimation+amount)*10) / 10
+	delayAnimation = math.Max(DELAY_MIN, math.Min(DELAY_MAX, delay))
+}
@@ -0,0 +1,158 @@
+package graphics
+
+import (
+	"autosimulator/src/machine"
+	"fmt"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Dica com as transições de um estado, mostrada quando o mouse para sobre
+// ele. Serve para maquinas com arestas densas demais para ler os rotulos.
+
+const (
+	// Tempo parado sobre o estado até a dica aparecer, em milissegundos
+	TOOLTIP_DELAY = 500
+
+	// Transições listadas. As demais são resumidas na ultima linha.
+	TOOLTIP_MAX_LINES = 15
+)
+
+type tooltip struct {
+	state   *graphicalState
+	machine machine.Machine
+	since   uint64
+
+	// Posição do mouse na tela
+	pos sdl.Point
+}
+
+// Procura o estado sob o mouse. world é a posição do mouse no mundo, a
+// mesma dos estados, e screen a posição na tela.
+func (tip *tooltip) hover(env *environment, world, screen sdl.Point) {
+	tip.pos = screen
+
+	state, m := hoveredState(env, world)
+	if state != tip.state {
+		tip.state = state
+		tip.machine = m
+		tip.since = sdl.GetTicks64()
+	}
+}
+
+func (tip *tooltip) clear() {
+	tip.state = nil
+	tip.machine = nil
+}
+
+// A dica não aparece enquanto um estado é arrastado ou quando há um painel
+// por cima do diagrama
+func (tip *tooltip) visible() bool {
+	if tip.state == nil || sdl.GetTicks64()-tip.since < TOOLTIP_DELAY {
+		return false
+	}
+
+	return !ui.menuMode && !ui.waitingFile && !ui.editor.active &&
+		!ui.tree.active && !ui.batch.active && !ui.dragInfo.leftMouseDown
+}
+
+func (tip *tooltip) draw(window *_SDLWindow) error {
+	text := tooltipText(tip.machine, tip.state)
+
+	maxLen := 0
+	for _, line := range text {
+		if len(line) > maxLen {
+			maxLen = len(line)
+		}
+	}
+
+	rect := sdl.Rect{
+		X: tip.pos.X + PADX*2,
+		Y: tip.pos.Y + PADY*2,
+		W: DIMENSAO_ESTRUTURAS / 2 * int32(maxLen+1),
+		H: DIMENSAO_ESTRUTURAS * int32(len(text)),
+	}
+
+	// Fica dentro da janela, do outro lado do mouse se preciso
+	if rect.X+rect.W > window.WIDTH {
+		rect.X = tip.pos.X - rect.W - PADX*2
+	}
+
+	if rect.Y+rect.H > window.HEIGHT {
+		rect.Y = window.HEIGHT - rect.H
+	}
+
+	if rect.X < 0 {
+		rect.X = 0
+	}
+
+	if rect.Y < 0 {
+		rect.Y = 0
+	}
+
+	err := drawRect(window.renderer, 1, rect, COLOR_DEFAULT, COLOR_BACKGROUD)
+	if err != nil {
+		return err
+	}
+
+	var spaceBetween int32 = DIMENSAO_ESTRUTURAS / 2
+	return drawText(window, text, spaceBetween, rect.X+PADX, rect.Y+DIMENSAO_ESTRUTURAS/2, maxLen, TEXT_DOWN_LEFT)
+}
+
+// Estado sob a posição e a maquina dele. Na comparação pode ser um estado da
+// segunda maquina.
+func hoveredState(env *environment, world sdl.Point) (*graphicalState, machine.Machine) {
+	for _, state := range ui.states {
+		if world.InRect(state.Rect) {
+			return state, env.machine
+		}
+	}
+
+	if ui.compare != nil {
+		for _, state := range ui.compare.states {
+			if world.InRect(state.Rect) {
+				return state, ui.compare.machine
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// Nome do estado, se é inicial ou final e as transições que saem dele
+func tooltipText(m machine.Machine, state *graphicalState) []string {
+	var kinds []string
+	if state.state == m.GetInitialState() {
+		kinds = append(kinds, "inicial")
+	}
+
+	for _, final := range m.GetFinalStates() {
+		if state.state == final {
+			kinds = append(kinds, "final")
+			break
+		}
+	}
+
+	title := state.state
+	if len(kinds) > 0 {
+		title += " (" + strings.Join(kinds, ", ") + ")"
+	}
+
+	text := []string{title}
+	transitions := m.GetTransitions(state.state)
+	if len(transitions) == 0 {
+		return append(text, "sem transições")
+	}
+
+	for i, t := range transitions {
+		if i == TOOLTIP_MAX_LINES {
+			text = append(text, fmt.Sprintf("... mais %d", len(transitions)-i))
+			break
+		}
+
+		text = append(text, t.Stringfy())
+	}
+
+	return text
+}
@@ -0,0 +1,249 @@
+package graphics
+
+import (
+	"autosimulator/src/machine"
+	"fmt"
+	"math"
+
+	"github.com/veandco/go-sdl2/gfx"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Painel com a arvore de computação da entrada atual, desenhado por cima do
+// diagrama. Cada nó é uma configuração da maquina e cada camada um simbolo
+// lido; as folhas têm a cor e a forma da situação do ramo. Clicar em um nó
+// reproduz o caminho até ele no diagrama, na fita e nas pilhas.
+
+const (
+	TREE_NODE_RADIUS = 4
+
+	// Distancia maxima do clique até o nó selecionado
+	TREE_CLICK_RADIUS = 8
+)
+
+type treeView struct {
+	active bool
+
+	// Calculada quando o painel é aberto. nil depois de trocar a maquina ou
+	// a entrada.
+	root     *machine.ComputationNode
+	selected *machine.ComputationNode
+
+	// Posição de cada nó no ultimo desenho, para o clique
+	positions map[*machine.ComputationNode]sdl.Point
+}
+
+func (tree *treeView) toggle(env *environment) {
+	tree.active = !tree.active
+	if tree.active && tree.root == nil {
+		tree.root = machine.ExecuteTree(env.machine, env.input, 0)
+		env.machine.GetInput().Reset()
+	}
+}
+
+// Esquece a arvore calculada, que é refeita na proxima abertura
+func (tree *treeView) clear() {
+	tree.root = nil
+	tree.selected = nil
+	tree.positions = nil
+}
+
+// Reproduz o ramo do nó clicado. Retorna false se o clique não foi em um nó.
+func (tree *treeView) handleClick(p sdl.Point, env *environment) bool {
+	var closest *machine.ComputationNode
+	closestDistance := math.Inf(1)
+	for node, position := range tree.positions {
+		distance := math.Hypot(float64(p.X-position.X), float64(p.Y-position.Y))
+		if distance <= TREE_CLICK_RADIUS && distance < closestDistance {
+			closest = node
+			closestDistance = distance
+		}
+	}
+
+	if closest == nil {
+		return false
+	}
+
+	tree.selected = closest
+	tree.active = false
+
+	ui.bufferComputation = *closest.Computation()
+	ui.indexComputation = 0
+	if ui.compare != nil {
+		ui.compare.diff()
+	}
+
+	env.running = len(ui.bufferComputation.History) > 1
+	fpsTimer = sdl.GetTicks64()
+	return true
+}
+
+func (tree *treeView) draw(window *_SDLWindow) error {
+	bounds := diagramBounds(window)
+	rect := sdl.Rect{X: bounds.X, Y: bounds.Y, W: bounds.W, H: bounds.H}
+	err := drawRect(window.renderer, 2, rect, COLOR_DEFAULT, COLOR_BACKGROUD)
+	if err != nil {
+		return err
+	}
+
+	leaves := tree.root.Leaves()
+	accepted := "rejeitada"
+	if tree.root.Accepted() {
+		accepted = "aceita"
+	}
+
+	header := fmt.Sprintf("arvore: %d ramos, %s", len(leaves), accepted)
+	maxLen := int(bounds.W/(DIMENSAO_ESTRUTURAS/2)) - 2
+	err = drawText(window, []string{header}, 0, rect.X+PADX*2, rect.Y+DIMENSAO_ESTRUTURAS/2+PADY, maxLen, TEXT_DOWN_LEFT)
+	if err != nil {
+		return err
+	}
+
+	// Legenda das cores, em uma linha abaixo do titulo
+	legend := []struct {
+		text   string
+		status int
+	}{
+		{"aceita", machine.BRANCH_ACCEPTED},
+		{"rejeitada", machine.BRANCH_REJECTED},
+		{"morta", machine.BRANCH_DEAD},
+		{"limite", machine.BRANCH_LIMIT},
+	}
+
+	legendY := rect.Y + DIMENSAO_ESTRUTURAS + DIMENSAO_ESTRUTURAS/2
+	itemWidth := (bounds.W - PADX*4) / int32(len(legend))
+	for i, item := range legend {
+		x := rect.X + PADX*2 + itemWidth*int32(i)
+		err = drawBranchNode(window.renderer, x+TREE_NODE_RADIUS, legendY, item.status)
+		if err != nil {
+			return err
+		}
+
+		err = drawText(window, []string{item.text}, 0, x+TREE_NODE_RADIUS*3, legendY, maxLen, TEXT_DOWN_LEFT)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Area dos nós, abaixo da legenda
+	area := sdl.Rect{
+		X: rect.X + PADX*2,
+		Y: legendY + DIMENSAO_ESTRUTURAS,
+		W: rect.W - PADX*4,
+		H: rect.H - (legendY - rect.Y) - DIMENSAO_ESTRUTURAS - PADY*2,
+	}
+
+	tree.positions = treePositions(tree.root, leaves, area)
+	return tree.drawNode(window, tree.root)
+}
+
+func (tree *treeView) drawNode(window *_SDLWindow, node *machine.ComputationNode) error {
+	position := tree.positions[node]
+	for _, child := range node.Children {
+		childPosition := tree.positions[child]
+		if ok := gfx.LineColor(window.renderer, position.X, position.Y, childPosition.X, childPosition.Y, COLOR_DEFAULT); !ok {
+			return fmt.Errorf("não foi possivel desenhar a aresta da arvore")
+		}
+
+		err := tree.drawNode(window, child)
+		if err != nil {
+			return err
+		}
+	}
+
+	if node == tree.selected {
+		drawCircle(window.renderer, position.X, position.Y, TREE_NODE_RADIUS*2, COLOR_HIGHLIGHT)
+	}
+
+	return drawBranchNode(window.renderer, position.X, position.Y, node.Status)
+}
+
+// Cada situação tem também uma forma, para não depender só da cor: aceita
+// com um anel, como os estados finais, rejeitada com um X, morta vazada e
+// cortada pelo limite quadrada
+func drawBranchNode(renderer *sdl.Renderer, x, y int32, status int) error {
+	color := branchColor(status)
+	r := int32(TREE_NODE_RADIUS)
+
+	var ok bool
+	switch status {
+	case machine.BRANCH_ACCEPTED:
+		ok = gfx.FilledCircleColor(renderer, x, y, r, color) &&
+			gfx.CircleColor(renderer, x, y, r+3, color)
+	case machine.BRANCH_REJECTED:
+		ok = gfx.ThickLineColor(renderer, x-r, y-r, x+r, y+r, 2, color) &&
+			gfx.ThickLineColor(renderer, x-r, y+r, x+r, y-r, 2, color)
+	case machine.BRANCH_DEAD:
+		ok = gfx.CircleColor(renderer, x, y, r, color)
+	case machine.BRANCH_LIMIT:
+		ok = gfx.BoxColor(renderer, x-r, y-r, x+r, y+r, color)
+	default:
+		ok = gfx.FilledCircleColor(renderer, x, y, r, color)
+	}
+
+	if !ok {
+		return fmt.Errorf("não foi possivel desenhar o nó da arvore")
+	}
+
+	return nil
+}
+
+// As folhas são distribuidas igualmente na largura e cada nó interno fica
+// no meio dos seus filhos. A profundidade desce pela altura.
+func treePositions(root *machine.ComputationNode, leaves []*machine.ComputationNode, area sdl.Rect) map[*machine.ComputationNode]sdl.Point {
+	positions := make(map[*machine.ComputationNode]sdl.Point)
+
+	maxDepth := 1
+	for _, leaf := range leaves {
+		if leaf.Depth > maxDepth {
+			maxDepth = leaf.Depth
+		}
+	}
+
+	levelHeight := area.H / int32(maxDepth)
+	if levelHeight > HEIGTH_REC {
+		levelHeight = HEIGTH_REC
+	}
+
+	leafIndex := make(map[*machine.ComputationNode]int)
+	for i, leaf := range leaves {
+		leafIndex[leaf] = i
+	}
+
+	var place func(node *machine.ComputationNode) int32
+	place = func(node *machine.ComputationNode) int32 {
+		var x int32
+		if len(node.Children) == 0 {
+			x = area.X + area.W*int32(2*leafIndex[node]+1)/int32(2*len(leaves))
+		} else {
+			first := place(node.Children[0])
+			last := first
+			for _, child := range node.Children[1:] {
+				last = place(child)
+			}
+
+			x = (first + last) / 2
+		}
+
+		positions[node] = sdl.Point{X: x, Y: area.Y + levelHeight*int32(node.Depth)}
+		return x
+	}
+
+	place(root)
+	return positions
+}
+
+func branchColor(status int) sdl.Color {
+	switch status {
+	case machine.BRANCH_ACCEPTED:
+		return COLOR_ACCEPTED
+	case machine.BRANCH_REJECTED:
+		return COLOR_REJECTED
+	case machine.BRANCH_DEAD:
+		return COLOR_WARNING
+	case machine.BRANCH_LIMIT:
+		return COLOR_INITIAL
+	default:
+		return COLOR_DEFAULT
+	}
+}
@@ -1,10 +1,12 @@
 package graphics
 
 import (
-	"autosimulator/src/reader"
 	"autosimulator/src/utils"
 	"errors"
 	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/veandco/go-sdl2/gfx"
 	"github.com/veandco/go-sdl2/sdl"
@@ -17,6 +19,12 @@ type SelectBox struct {
 	MaxItems     int32
 	MaxLen       int
 	Options      []string
+
+	// Primeira opção visivel, para listas maiores que MaxItems
+	Offset int32
+
+	// Navegação de arquivos. nil nos menus que não listam arquivos.
+	Files *fileBrowser
 }
 
 const (
@@ -30,6 +38,11 @@ const (
 	TEXT_UP_CENTER    = iota
 )
 
+const (
+	// Tempo do aviso de status na tela, em milissegundos
+	STATUS_DURATION = 3000
+)
+
 const (
 	TAMANHO_ESTRUTURAS        = 9
 	DIMENSAO_ESTRUTURAS       = 32
@@ -39,7 +52,7 @@ const (
 
 func (ui *uiComponents) waitForFile(window *_SDLWindow) error {
 	text := "Aguardando o arquivo..."
-	err := drawText(window, []string{text}, 0, WITDH/2, HEIGHT/2, len(text), TEXT_UP_CENTER)
+	err := drawText(window, []string{text}, 0, window.WIDTH/2, window.HEIGHT/2, len(text), TEXT_UP_CENTER)
 	if err != nil {
 		return err
 	}
@@ -48,29 +61,64 @@ func (ui *uiComponents) waitForFile(window *_SDLWindow) error {
 }
 
 func (sb *SelectBox) draw(window *_SDLWindow) error {
+	lenOptions := int32(len(sb.Options))
+	if sb.CurrentIndex > lenOptions {
+		sb.CurrentIndex = lenOptions
+	}
+
 	if sb.CurrentIndex < 1 {
 		sb.CurrentIndex = 1
 	}
 
-	if sb.CurrentIndex > sb.MaxItems {
-		sb.CurrentIndex = sb.MaxItems
+	// A parte visivel acompanha a seleção
+	if sb.CurrentIndex <= sb.Offset {
+		sb.Offset = sb.CurrentIndex - 1
 	}
 
-	lenOptions := int32(len(sb.Options))
-	if sb.CurrentIndex > lenOptions {
-		sb.CurrentIndex = lenOptions
+	if sb.CurrentIndex > sb.Offset+sb.MaxItems {
+		sb.Offset = sb.CurrentIndex - sb.MaxItems
 	}
 
-	err := drawBoxListShadow(window, *sb.Rect, sb.MaxItems, sb.CurrentIndex)
+	err := drawBoxListShadow(window, *sb.Rect, sb.MaxItems, sb.CurrentIndex-sb.Offset)
 	if err != nil {
 		return err
 	}
 
-	err = drawText(window, sb.Options, DIMENSAO_ESTRUTURAS/2, sb.X+PADX, sb.Y+sb.H/2, sb.MaxLen, TEXT_DOWN_LEFT)
+	visible := sb.Options[sb.Offset:]
+	if int32(len(visible)) > sb.MaxItems {
+		visible = visible[:sb.MaxItems]
+	}
+
+	if len(visible) == 0 {
+		visible = []string{"(vazio)"}
+	}
+
+	err = drawText(window, visible, DIMENSAO_ESTRUTURAS/2, sb.X+PADX, sb.Y+sb.H/2, sb.MaxLen, TEXT_DOWN_LEFT)
 	if err != nil {
 		return err
 	}
 
+	// Barra de rolagem quando nem todas as opções cabem
+	if lenOptions > sb.MaxItems {
+		listHeight := sb.H * sb.MaxItems
+		bar := sdl.Rect{
+			X: sb.X + sb.W - PADX*2,
+			Y: sb.Y + listHeight*sb.Offset/lenOptions,
+			W: PADX,
+			H: listHeight * sb.MaxItems / lenOptions,
+		}
+
+		err = window.renderer.SetDrawColor(COLOR_DEFAULT.R, COLOR_DEFAULT.G, COLOR_DEFAULT.B, COLOR_DEFAULT.A)
+		if err != nil {
+			return err
+		}
+
+		err = window.renderer.FillRect(&bar)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -80,22 +128,20 @@ func (ui *uiComponents) drawMenu(window *_SDLWindow) error {
 	switch menuType {
 	case "main":
 		err = drawMainMenu(window, ui.menuInfo.currentMenu)
-	case "explorer":
-		err = drawExplorerMenu(window, ui.menuInfo.currentMenu)
+	case "explorer", "load_input", "batch_input":
+		err = drawFileMenu(window, ui.menuInfo.currentMenu)
 	case "input":
 		err = drawInputField(window)
-	case "load_input":
-		err = drawLoadInputMenu(window, ui.menuInfo.currentMenu)
 	default:
 	}
 
 	return err
 }
 
-func drawExplorerMenu(window *_SDLWindow, menuBox *SelectBox) error {
-	// TODO: guardar até fechar
+// Menu de arquivos com o diretorio atual e o filtro acima da lista
+func drawFileMenu(window *_SDLWindow, menuBox *SelectBox) error {
 	if menuBox.Options == nil {
-		options, err := reader.GetJsonList(EXAMPLES_PATH)
+		options, err := menuBox.Files.options()
 		if err != nil {
 			return err
 		}
@@ -105,32 +151,31 @@ func drawExplorerMenu(window *_SDLWindow, menuBox *SelectBox) error {
 
 	var widthBox int32 = DIMENSAO_ESTRUTURAS * 12
 	rect := sdl.Rect{
-		X: WITDH/2 - widthBox/2,
-		Y: HEIGHT * 0.1,
+		X: window.WIDTH/2 - widthBox/2,
+		Y: window.HEIGHT / 10,
 		W: widthBox,
 		H: DIMENSAO_ESTRUTURAS,
 	}
 
-	menuBox.Rect = &rect
-	return menuBox.draw(window)
-}
+	filterRect := rect
+	filterRect.Y -= rect.H + PADY*2
+	err := drawRect(window.renderer, 2, filterRect, COLOR_DEFAULT, COLOR_BACKGROUD)
+	if err != nil {
+		return err
+	}
 
-func drawLoadInputMenu(window *_SDLWindow, menuBox *SelectBox) error {
-	if menuBox.Options == nil {
-		options, err := reader.GetCsvList(INPUT_PATH)
-		if err != nil {
-			return err
+	// O fim do caminho é o mais importante quando não cabe
+	header := filepath.Join(menuBox.Files.root(), menuBox.Files.dir) + "/ " + menuBox.Files.filter + "_"
+	if len(header) > menuBox.MaxLen {
+		header = header[len(header)-menuBox.MaxLen:]
+		for !utf8.RuneStart(header[0]) {
+			header = header[1:]
 		}
-
-		menuBox.Options = options
 	}
 
-	var widthBox int32 = DIMENSAO_ESTRUTURAS * 12
-	rect := sdl.Rect{
-		X: WITDH/2 - widthBox/2,
-		Y: HEIGHT * 0.1,
-		W: widthBox,
-		H: DIMENSAO_ESTRUTURAS,
+	err = drawText(window, []string{header}, 0, filterRect.X+PADX, filterRect.Y+filterRect.H/2, menuBox.MaxLen, TEXT_DOWN_LEFT)
+	if err != nil {
+		return err
 	}
 
 	menuBox.Rect = &rect
@@ -140,8 +185,8 @@ func drawLoadInputMenu(window *_SDLWindow, menuBox *SelectBox) error {
 func drawMainMenu(window *_SDLWindow, menuBox *SelectBox) error {
 	var widthBox int32 = DIMENSAO_ESTRUTURAS * 6
 	rect := sdl.Rect{
-		X: WITDH/2 - widthBox/2,
-		Y: HEIGHT/2 - DIMENSAO_ESTRUTURAS*menuBox.MaxItems/2,
+		X: window.WIDTH/2 - widthBox/2,
+		Y: window.HEIGHT/2 - DIMENSAO_ESTRUTURAS*menuBox.MaxItems/2,
 		W: widthBox,
 		H: DIMENSAO_ESTRUTURAS,
 	}
@@ -190,53 +235,22 @@ func drawInputField(window *_SDLWindow) error {
 }
 
 func (ui *uiComponents) drawFita(window *_SDLWindow) error {
-	// Calculo da posicao inicial da fita/texto
+	// Calculo da posicao inicial da fita
 	var fitaCellWidth int32 = DIMENSAO_ESTRUTURAS
 	x := window.WIDTH - PADX*5 - (fitaCellWidth * (TAMANHO_ESTRUTURAS + 8))
 	y := window.HEIGHT - fitaCellWidth - PADY
-
-	// Rec representa o primeiro quadrado da fita
-	fitaRec := sdl.Rect{
-		X: x,
-		Y: y,
-		W: fitaCellWidth,
-		H: fitaCellWidth, // É um quadrado
-	}
-
-	err := drawManyRects(window.renderer, int(TAMANHO_ESTRUTURAS), RIGHT, fitaRec, COLOR_DEFAULT, COLOR_BACKGROUD)
-	if err != nil {
-		return err
-	}
-
-	// Head da fita
-	headBase := fitaCellWidth / 2
-	headHeigth := headBase / 2
-	err = drawArrowDown(window.renderer, (x + fitaCellWidth/2), (y - PADY), headBase, headHeigth, COLOR_DEFAULT)
-	if err != nil {
-		return err
-	}
-
-	// Texto
-	bufferFita := ui.bufferInput
-	err = drawText(window, bufferFita, fitaCellWidth, (x + fitaCellWidth/2), y, 1, TEXT_RIGHT_CENTER)
-	if err != nil {
-		return err
-	}
-
-	// Para manter uma referencia dos ponteiros que vou precisar liberar
-	return nil
+	return ui.tape.draw(window, x, y, ui.fired)
 }
 
 func (ui *uiComponents) drawStacks(window *_SDLWindow) error {
-	var err error
-	a, b := ui.stackHist.get(ui.indexComputation)
-	err = ui.drawStack(window, a, 1)
-	if err != nil {
-		return err
-	}
+	// As pilhas vêm do passo atual, que pode ser de um ramo da arvore
+	record := ui.bufferComputation.History[ui.step()]
+	for i, stack := range record.Stacks() {
+		if len(stack) > TAMANHO_ESTRUTURAS {
+			stack = stack[len(stack)-TAMANHO_ESTRUTURAS:]
+		}
 
-	if b != nil {
-		err = ui.drawStack(window, b, 2)
+		err := ui.drawStack(window, stack, int32(i+1))
 		if err != nil {
 			return err
 		}
@@ -304,7 +318,7 @@ func (ui *uiComponents) drawHist(window *_SDLWindow) error {
 }
 
 func (ui *uiComponents) drawHistText(window *_SDLWindow, x, y int32) error {
-	index := ui.indexComputation
+	index := ui.step()
 
 	var upper string = "---"
 	if index > 0 {
@@ -325,6 +339,41 @@ func (ui *uiComponents) drawHistText(window *_SDLWindow, x, y int32) error {
 	return drawText(window, []string{upper, mid, bottom}, spaceBetween, xCoor, yCoor, maxLen, TEXT_DOWN_LEFT)
 }
 
+// Mensagem mostrada acima dos avisos do alfabeto por STATUS_DURATION
+type statusMessage struct {
+	text  string
+	until uint64
+}
+
+func (ui *uiComponents) setStatus(text string) {
+	ui.status = statusMessage{text: text, until: sdl.GetTicks64() + STATUS_DURATION}
+}
+
+// Aviso de status e simbolos da entrada fora do alfabeto, no canto da tela
+func (ui *uiComponents) drawWarnings(window *_SDLWindow) error {
+	var text []string
+	if sdl.GetTicks64() < ui.status.until {
+		text = append(text, ui.status.text)
+	}
+
+	invalid := ui.bufferComputation.InvalidSymbols
+	if len(invalid) > 0 {
+		text = append(text, "Fora do alfabeto:")
+	}
+
+	for _, v := range invalid {
+		text = append(text, fmt.Sprintf("'%s' na posição %d", v.Symbol, v.Position))
+	}
+
+	if len(text) == 0 {
+		return nil
+	}
+
+	maxLen := int(window.WIDTH/(DIMENSAO_ESTRUTURAS/2)) - 2
+	var spaceBetween int32 = DIMENSAO_ESTRUTURAS / 2
+	return drawText(window, text, spaceBetween, PADX, PADY+DIMENSAO_ESTRUTURAS/2, maxLen, TEXT_DOWN_LEFT)
+}
+
 func drawBoxList(window *_SDLWindow, rect sdl.Rect, amount, headPos int32) error {
 	if headPos > amount {
 		return fmt.Errorf("a posicao da cabeça da seta não pode ser maior que a quantidade de elementos na BoxList")
@@ -348,10 +397,11 @@ func drawBoxList(window *_SDLWindow, rect sdl.Rect, amount, headPos int32) error
 }
 
 func drawBoxListShadow(window *_SDLWindow, rect sdl.Rect, amount, headPos int32) error {
+	// Sombra mais escura que o fundo do tema
 	colorShadow := sdl.Color{
-		R: 10,
-		G: 10,
-		B: 10,
+		R: COLOR_BACKGROUD.R / 2,
+		G: COLOR_BACKGROUD.G / 2,
+		B: COLOR_BACKGROUD.B / 2,
 		A: COLOR_BACKGROUD.A,
 	}
 
@@ -424,10 +474,7 @@ func drawText(window *_SDLWindow, text []string, space, x1, y1 int32, maxLen, di
 			return err
 		}
 
-		_, _, fontW, fontH, err := textTexture.Query()
-		if err != nil {
-			return err
-		}
+		fontW, fontH := window.textSize(textSurface)
 
 		switch direction {
 		case TEXT_UP_CENTER:
@@ -451,6 +498,7 @@ func drawText(window *_SDLWindow, text []string, space, x1, y1 int32, maxLen, di
 		}
 
 		window.renderer.Copy(textTexture, nil, textRect)
+		textTexture.Destroy()
 		if err != nil {
 			return err
 		}
@@ -540,3 +588,91 @@ func drawArrowDown(renderer *sdl.Renderer, x, y, base, heigth int32, color sdl.C
 // 	gfx.ThickLineColor(window.renderer, 0, HEIGHT/2, WITDH, HEIGHT/2, 2, COLOR_DEFAULT)
 // 	gfx.ThickLineColor(window.renderer, WITDH/2, 0, WITDH/2, HEIGHT, 2, COLOR_DEFAULT)
 // }
+
+func (panel *errorPanel) draw(window *_SDLWindow) error {
+	maxLen := 40
+	text := []string{"ERRO"}
+	if panel.path != "" {
+		text = append(text, wrapText("arquivo: "+panel.path, maxLen)...)
+	}
+
+	text = append(text, wrapText(panel.message, maxLen)...)
+	text = append(text, " ", "qualquer tecla: fechar")
+
+	// Cada linha do drawText ocupa spaceBetween mais meia altura da fonte
+	var spaceBetween int32 = DIMENSAO_ESTRUTURAS / 2
+	var width int32 = DIMENSAO_ESTRUTURAS * 12
+	height := DIMENSAO_ESTRUTURAS * int32(len(text)+1)
+	rect := sdl.Rect{
+		X: window.WIDTH/2 - width/2,
+		Y: window.HEIGHT/2 - height/2,
+		W: width,
+		H: height,
+	}
+
+	err := drawRect(window.renderer, 2, rect, COLOR_ERROR, COLOR_BACKGROUD)
+	if err != nil {
+		return err
+	}
+
+	return drawText(window, text, spaceBetween, rect.X+PADX*2, rect.Y+DIMENSAO_ESTRUTURAS, maxLen, TEXT_DOWN_LEFT)
+}
+
+// Versão do painel de erro que não bloqueia a interface, no topo da janela
+func (panel *errorPanel) drawBanner(window *_SDLWindow) error {
+	maxLen := int(window.WIDTH/(DIMENSAO_ESTRUTURAS/2)) - 2
+	text := wrapText(fmt.Sprintf("recarga de %s: %s", panel.path, panel.message), maxLen)
+
+	var spaceBetween int32 = DIMENSAO_ESTRUTURAS / 2
+	rect := sdl.Rect{
+		X: PADX,
+		Y: PADY,
+		W: window.WIDTH - PADX*2,
+		H: DIMENSAO_ESTRUTURAS * int32(len(text)),
+	}
+
+	err := drawRect(window.renderer, 2, rect, COLOR_ERROR, COLOR_BACKGROUD)
+	if err != nil {
+		return err
+	}
+
+	return drawText(window, text, spaceBetween, rect.X+PADX, rect.Y+DIMENSAO_ESTRUTURAS/2, maxLen, TEXT_DOWN_LEFT)
+}
+
+// Quebra o texto em linhas de até maxLen bytes, nos espaços quando possivel
+func wrapText(text string, maxLen int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		for len(word) > maxLen {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+
+			cut := maxLen
+			for !utf8.RuneStart(word[cut]) {
+				cut--
+			}
+
+			lines = append(lines, word[:cut])
+			word = word[cut:]
+		}
+
+		switch {
+		case line == "":
+			line = word
+		case len(line)+1+len(word) <= maxLen:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+
+	if line != "" {
+		lines = append(lines, line)
+	}
+
+	return lines
+}
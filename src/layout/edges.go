@@ -0,0 +1,187 @@
+package layout
+
+import (
+	"autosimulator/src/machine"
+	"math"
+	"strings"
+)
+
+// Geometria das arestas do diagrama, compartilhada pela interface grafica e
+// pelo renderizador em SVG. As transições entre o mesmo par de estados são
+// desenhadas como uma unica aresta, com um rotulo por transição.
+
+type (
+	// Curva de Bezier quadratica. Arestas retas têm o controle no meio.
+	Curve struct {
+		Start, Control, End machine.Position
+	}
+
+	point struct {
+		X, Y float64
+	}
+)
+
+// Alinhamento horizontal do texto dos rotulos em relação ao ponto
+const (
+	ALIGN_START  = iota
+	ALIGN_MIDDLE = iota
+	ALIGN_END    = iota
+)
+
+// Destinos das transições de cada estado da maquina
+func Edges(m machine.Machine) map[string][]string {
+	edges := make(map[string][]string)
+	for _, state := range m.GetStates() {
+		for _, t := range m.GetTransitions(state) {
+			edges[state] = append(edges[state], t.GetResultState())
+		}
+	}
+
+	return edges
+}
+
+// Rotulos das transições de cada estado, agrupados pelo estado destino
+func Labels(m machine.Machine) map[string]map[string][]string {
+	labels := make(map[string]map[string][]string)
+	for _, state := range m.GetStates() {
+		labels[state] = make(map[string][]string)
+		for _, t := range m.GetTransitions(state) {
+			next := t.GetResultState()
+			labels[state][next] = append(labels[state][next], Label(t))
+		}
+	}
+
+	return labels
+}
+
+// Rotulo da transição sem o estado destino, já indicado pela aresta
+func Label(t machine.Transition) string {
+	return LabelOf(t.Stringfy())
+}
+
+// Mesmo que Label, a partir da transição já formatada (como no historico da
+// computação): "(a, &, b, q1)" vira "a, &, b"
+func LabelOf(transition string) string {
+	inner := strings.TrimSuffix(strings.TrimPrefix(transition, "("), ")")
+	i := strings.LastIndex(inner, ",")
+	if i < 0 {
+		return inner
+	}
+
+	return strings.TrimSpace(inner[:i])
+}
+
+// Aresta entre os centros de dois estados de raio radius. bend desloca o
+// controle para a esquerda do sentido da aresta, assim as arestas A->B e B->A
+// ficam em lados opostos; com bend 0 a aresta é reta.
+func EdgeCurve(from, to machine.Position, radius, bend float64) Curve {
+	a, b := toPoint(from), toPoint(to)
+	u := unit(point{b.X - a.X, b.Y - a.Y})
+	normal := point{u.Y, -u.X}
+	control := point{(a.X+b.X)/2 + normal.X*bend, (a.Y+b.Y)/2 + normal.Y*bend}
+
+	// A aresta começa e termina na borda dos estados, na direção do controle
+	toStart := unit(point{control.X - a.X, control.Y - a.Y})
+	toEnd := unit(point{control.X - b.X, control.Y - b.Y})
+	return Curve{
+		Start:   toPosition(point{a.X + toStart.X*radius, a.Y + toStart.Y*radius}),
+		Control: toPosition(control),
+		End:     toPosition(point{b.X + toEnd.X*radius, b.Y + toEnd.Y*radius}),
+	}
+}
+
+// Laço de um estado para ele mesmo, acima do estado
+func SelfLoop(center machine.Position, radius float64) Curve {
+	c := toPoint(center)
+	angle := math.Pi / 5
+	return Curve{
+		Start:   toPosition(point{c.X - radius*math.Sin(angle), c.Y - radius*math.Cos(angle)}),
+		Control: toPosition(point{c.X, c.Y - radius*3.5}),
+		End:     toPosition(point{c.X + radius*math.Sin(angle), c.Y - radius*math.Cos(angle)}),
+	}
+}
+
+// Ponto da curva em t, de 0 (inicio) a 1 (fim)
+func (c Curve) Point(t float64) machine.Position {
+	s, k, e := toPoint(c.Start), toPoint(c.Control), toPoint(c.End)
+	a, b, d := (1-t)*(1-t), 2*(1-t)*t, t*t
+	return toPosition(point{a*s.X + b*k.X + d*e.X, a*s.Y + b*k.Y + d*e.Y})
+}
+
+// Pontos da curva divididos em segments segmentos de reta
+func (c Curve) Points(segments int) []machine.Position {
+	points := make([]machine.Position, 0, segments+1)
+	for i := 0; i <= segments; i++ {
+		points = append(points, c.Point(float64(i)/float64(segments)))
+	}
+
+	return points
+}
+
+// Posição de cada linha do rotulo (centro vertical) e o alinhamento do texto.
+// O rotulo fica a gap pixels do meio da curva, do lado para onde ela se
+// curva, ou à esquerda do sentido da aresta se ela for reta.
+func (c Curve) LabelLines(gap float64, lines int, lineHeight int32) ([]machine.Position, int) {
+	s, k, e := toPoint(c.Start), toPoint(c.Control), toPoint(c.End)
+	bulge := point{k.X - (s.X+e.X)/2, k.Y - (s.Y+e.Y)/2}
+	direction := unit(bulge)
+
+	// Arestas retas têm o controle no meio, a menos do arredondamento
+	if math.Hypot(bulge.X, bulge.Y) < 2 {
+		u := unit(point{e.X - s.X, e.Y - s.Y})
+		direction = point{u.Y, -u.X}
+	}
+
+	middle := toPoint(c.Point(0.5))
+	anchor := toPosition(point{middle.X + direction.X*gap, middle.Y + direction.Y*gap})
+
+	align := ALIGN_MIDDLE
+	if direction.X > 0.3 {
+		align = ALIGN_START
+	} else if direction.X < -0.3 {
+		align = ALIGN_END
+	}
+
+	// As linhas crescem para longe da aresta
+	height := lineHeight * int32(lines)
+	top := anchor.Y - height/2
+	if direction.Y < -0.3 {
+		top = anchor.Y - height
+	} else if direction.Y > 0.3 {
+		top = anchor.Y
+	}
+
+	positions := make([]machine.Position, lines)
+	for i := range positions {
+		positions[i] = machine.Position{X: anchor.X, Y: top + lineHeight*int32(i) + lineHeight/2}
+	}
+
+	return positions, align
+}
+
+// Vertices da ponta de seta no fim da curva: ponta, esquerda e direita
+func (c Curve) ArrowHead(size float64) (machine.Position, machine.Position, machine.Position) {
+	k, e := toPoint(c.Control), toPoint(c.End)
+	u := unit(point{e.X - k.X, e.Y - k.Y})
+	base := point{e.X - u.X*size, e.Y - u.Y*size}
+	left := point{base.X - u.Y*size/2, base.Y + u.X*size/2}
+	right := point{base.X + u.Y*size/2, base.Y - u.X*size/2}
+	return c.End, toPosition(left), toPosition(right)
+}
+
+func unit(p point) point {
+	length := math.Hypot(p.X, p.Y)
+	if length == 0 {
+		return point{}
+	}
+
+	return point{p.X / length, p.Y / length}
+}
+
+func toPoint(p machine.Position) point {
+	return point{float64(p.X), float64(p.Y)}
+}
+
+func toPosition(p point) machine.Position {
+	return machine.Position{X: int32(math.Round(p.X)), Y: int32(math.Round(p.Y))}
+}
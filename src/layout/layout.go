@@ -0,0 +1,247 @@
+package layout
+
+import (
+	"autosimulator/src/machine"
+	"autosimulator/src/utils"
+	"math"
+	"sort"
+)
+
+// Posicionamento automatico dos estados no diagrama. Os estados são
+// organizados em camadas pela distancia (BFS) ao estado inicial, da esquerda
+// para a direita. A ordem dentro de cada camada é ajustada pela heuristica
+// do baricentro para diminuir os cruzamentos das transições e, por fim, os
+// estados muito proximos são afastados para não se sobreporem.
+
+const (
+	// Quantas vezes as camadas são reordenadas pelo baricentro
+	ORDER_SWEEPS = 4
+
+	// Iterações para afastar os estados sobrepostos
+	SEPARATE_ITERATIONS = 100
+)
+
+type Bounds struct {
+	X, Y, W, H int32
+}
+
+// Retorna o centro de cada estado dentro de bounds. edges mapeia cada estado
+// para os estados destino das suas transições.
+func Layered(states []string, initial string, edges map[string][]string, bounds Bounds, nodeSize int32) map[string]machine.Position {
+	positions := make(map[string]machine.Position)
+	if len(states) == 0 {
+		return positions
+	}
+
+	layers := layersByDepth(states, initial, edges)
+	orderLayers(layers, neighbors(states, edges))
+	place(layers, bounds, nodeSize, positions)
+	separate(states, bounds, nodeSize, positions)
+	return positions
+}
+
+// Agrupa os estados pela distancia ao estado inicial. Estados inalcançaveis
+// ficam nas camadas seguintes, a partir de uma nova busca.
+func layersByDepth(states []string, initial string, edges map[string][]string) [][]string {
+	depth := make(map[string]int)
+	bfs := func(start string, startDepth int) int {
+		maxDepth := startDepth
+		depth[start] = startDepth
+		queue := []string{start}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			for _, next := range edges[current] {
+				if _, visited := depth[next]; visited || !utils.Contains(states, next) {
+					continue
+				}
+
+				depth[next] = depth[current] + 1
+				if depth[next] > maxDepth {
+					maxDepth = depth[next]
+				}
+
+				queue = append(queue, next)
+			}
+		}
+
+		return maxDepth
+	}
+
+	nextDepth := 0
+	if utils.Contains(states, initial) {
+		nextDepth = bfs(initial, 0) + 1
+	}
+
+	for _, state := range states {
+		if _, visited := depth[state]; !visited {
+			nextDepth = bfs(state, nextDepth) + 1
+		}
+	}
+
+	layers := make([][]string, nextDepth)
+	for _, state := range states {
+		layers[depth[state]] = append(layers[depth[state]], state)
+	}
+
+	// Camadas vazias podem sobrar das buscas
+	var result [][]string
+	for _, layer := range layers {
+		if len(layer) > 0 {
+			result = append(result, layer)
+		}
+	}
+
+	return result
+}
+
+// Vizinhos sem direção e sem laços, usados pelo baricentro
+func neighbors(states []string, edges map[string][]string) map[string][]string {
+	result := make(map[string][]string)
+	for _, from := range states {
+		for _, to := range edges[from] {
+			if from == to || !utils.Contains(states, to) {
+				continue
+			}
+
+			result[from] = append(result[from], to)
+			result[to] = append(result[to], from)
+		}
+	}
+
+	return result
+}
+
+// Ordena cada camada pela posição media dos vizinhos na camada anterior
+// (descendo) e na seguinte (subindo)
+func orderLayers(layers [][]string, neighbors map[string][]string) {
+	index := make(map[string]int)
+	updateIndex := func(layer []string) {
+		for i, state := range layer {
+			index[state] = i
+		}
+	}
+
+	for _, layer := range layers {
+		updateIndex(layer)
+	}
+
+	sortLayer := func(layer, reference []string) {
+		barycenter := make(map[string]float64)
+		for _, state := range layer {
+			sum, count := 0.0, 0
+			for _, n := range neighbors[state] {
+				if utils.Contains(reference, n) {
+					sum += float64(index[n])
+					count++
+				}
+			}
+
+			if count == 0 {
+				barycenter[state] = float64(index[state])
+			} else {
+				barycenter[state] = sum / float64(count)
+			}
+		}
+
+		sort.SliceStable(layer, func(i, j int) bool {
+			return barycenter[layer[i]] < barycenter[layer[j]]
+		})
+
+		updateIndex(layer)
+	}
+
+	for sweep := 0; sweep < ORDER_SWEEPS; sweep++ {
+		for i := 1; i < len(layers); i++ {
+			sortLayer(layers[i], layers[i-1])
+		}
+
+		for i := len(layers) - 2; i >= 0; i-- {
+			sortLayer(layers[i], layers[i+1])
+		}
+	}
+}
+
+// Distribui as camadas em colunas. Quando não cabem na largura as colunas
+// quebram em faixas, alternando o sentido para manter camadas vizinhas
+// proximas.
+func place(layers [][]string, bounds Bounds, nodeSize int32, positions map[string]machine.Position) {
+	maxColumns := int(bounds.W / (nodeSize * 2))
+	if maxColumns < 1 {
+		maxColumns = 1
+	}
+
+	bands := (len(layers) + maxColumns - 1) / maxColumns
+	columns := (len(layers) + bands - 1) / bands
+	bandHeight := bounds.H / int32(bands)
+
+	for i, layer := range layers {
+		band := i / columns
+		column := i % columns
+		if band%2 == 1 {
+			column = columns - 1 - column
+		}
+
+		x := bounds.X + bounds.W*int32(2*column+1)/int32(2*columns)
+		for j, state := range layer {
+			y := bounds.Y + bandHeight*int32(band) + bandHeight*int32(2*j+1)/int32(2*len(layer))
+			positions[state] = machine.Position{X: x, Y: y}
+		}
+	}
+}
+
+// Afasta os pares de estados mais proximos que a distancia minima
+func separate(states []string, bounds Bounds, nodeSize int32, positions map[string]machine.Position) {
+	minDistance := float64(nodeSize) * 1.5
+	for iteration := 0; iteration < SEPARATE_ITERATIONS; iteration++ {
+		moved := false
+		for i, a := range states {
+			for _, b := range states[i+1:] {
+				pa, pb := positions[a], positions[b]
+				dx := float64(pb.X - pa.X)
+				dy := float64(pb.Y - pa.Y)
+				distance := math.Hypot(dx, dy)
+				if distance >= minDistance {
+					continue
+				}
+
+				// Estados no mesmo ponto são afastados na horizontal
+				if distance == 0 {
+					dx, dy, distance = 1, 0, 1
+				}
+
+				push := (minDistance - distance) / 2
+				offsetX := int32(math.Ceil(dx / distance * push))
+				offsetY := int32(math.Ceil(dy / distance * push))
+				positions[a] = clamp(machine.Position{X: pa.X - offsetX, Y: pa.Y - offsetY}, bounds, nodeSize)
+				positions[b] = clamp(machine.Position{X: pb.X + offsetX, Y: pb.Y + offsetY}, bounds, nodeSize)
+				moved = true
+			}
+		}
+
+		if !moved {
+			return
+		}
+	}
+}
+
+func clamp(p machine.Position, bounds Bounds, nodeSize int32) machine.Position {
+	half := nodeSize / 2
+	if p.X < bounds.X+half {
+		p.X = bounds.X + half
+	}
+
+	if p.X > bounds.X+bounds.W-half {
+		p.X = bounds.X + bounds.W - half
+	}
+
+	if p.Y < bounds.Y+half {
+		p.Y = bounds.Y + half
+	}
+
+	if p.Y > bounds.Y+bounds.H-half {
+		p.Y = bounds.Y + bounds.H - half
+	}
+
+	return p
+}
@@ -4,7 +4,6 @@ import (
 	"autosimulator/src/collections"
 	"autosimulator/src/machine"
 	"autosimulator/src/utils"
-	"errors"
 	"fmt"
 )
 
@@ -80,6 +79,22 @@ func (m *Machine) GetInput() *collections.Fita {
 	return m.Input
 }
 
+func (m *Machine) GetAlfabet() []string {
+	return m.Alfabet
+}
+
+func (m *Machine) GetLayout() map[string]machine.Position {
+	return m.Layout
+}
+
+func (m *Machine) Snapshot() machine.Snapshot {
+	return machine.Snapshot{State: m.currentState}
+}
+
+func (m *Machine) Restore(s machine.Snapshot) {
+	m.currentState = s.State
+}
+
 func (t *Transition) GetSymbol() string {
 	return t.Symbol
 }
@@ -100,19 +115,13 @@ func (t *Transition) MakeTransition(m machine.Machine) bool {
 }
 
 func (t *Transition) UnmarshalJSON(data []byte) error {
-	parsed, err := utils.ParseTransition((string(data)))
+	parsed, err := utils.ParseTransition(string(data), 2)
 	if err != nil {
-		return err
-	}
-
-	if len(parsed) > 2 {
-		err = errors.New(
-			`transições de maquinas AFD devem seguir o padrao:
+		return fmt.Errorf(
+			`%s. transições de maquinas AFD devem seguir o padrao:
 				"<estadoAtual>":[
 					"(<simbolo>, <proximoEstado>)"
-					],`)
-
-		return err
+					],`, err)
 	}
 
 	*t = Transition{
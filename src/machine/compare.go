@@ -0,0 +1,88 @@
+package machine
+
+import (
+	"autosimulator/src/collections"
+	"fmt"
+	"reflect"
+)
+
+// Motivos da divergencia entre duas computações da mesma entrada
+const (
+	DIVERGE_STACKS = iota
+	DIVERGE_HALT   = iota
+	DIVERGE_RESULT = iota
+)
+
+// Primeiro passo em que duas computações da mesma entrada diferem. Os
+// estados não são comparados, já que maquinas equivalentes podem ter nomes
+// de estados diferentes.
+type Divergence struct {
+	Step   int `json:"step"`
+	Reason int `json:"reason"`
+}
+
+// Compara as computações passo a passo. Retorna nil se as duas têm as
+// mesmas pilhas em todos os passos, param juntas e têm o mesmo resultado.
+func Compare(a, b *Computation) *Divergence {
+	steps := len(a.History)
+	if len(b.History) < steps {
+		steps = len(b.History)
+	}
+
+	for i := 0; i < steps; i++ {
+		if !reflect.DeepEqual(normalizeStacks(a.History[i].stacks), normalizeStacks(b.History[i].stacks)) {
+			return &Divergence{Step: i, Reason: DIVERGE_STACKS}
+		}
+	}
+
+	// Uma das maquinas parou antes da outra
+	if len(a.History) != len(b.History) {
+		return &Divergence{Step: steps, Reason: DIVERGE_HALT}
+	}
+
+	if a.Accepted() != b.Accepted() {
+		return &Divergence{Step: steps - 1, Reason: DIVERGE_RESULT}
+	}
+
+	return nil
+}
+
+// Pilhas vazias e ausentes são iguais: uma maquina sem pilhas e uma com a
+// pilha vazia estão na mesma configuração. O fundo da pilha é ignorado, já
+// que toda pilha o tem.
+func normalizeStacks(stacks [][]string) [][]string {
+	var result [][]string
+	for _, stack := range stacks {
+		if len(stack) > 0 && stack[0] == collections.FUNDO_PILHA {
+			stack = stack[1:]
+		}
+
+		if len(stack) == 0 {
+			stack = nil
+		}
+
+		result = append(result, stack)
+	}
+
+	for len(result) > 0 && result[len(result)-1] == nil {
+		result = result[:len(result)-1]
+	}
+
+	// Sem pilhas é nil, como o de uma maquina que não tem pilhas
+	if len(result) == 0 {
+		return nil
+	}
+
+	return result
+}
+
+func (d *Divergence) Stringfy() string {
+	switch d.Reason {
+	case DIVERGE_STACKS:
+		return fmt.Sprintf("pilhas diferentes no passo %d", d.Step)
+	case DIVERGE_HALT:
+		return fmt.Sprintf("uma das maquinas parou no passo %d", d.Step-1)
+	default:
+		return fmt.Sprintf("resultados diferentes no passo %d", d.Step)
+	}
+}
@@ -0,0 +1,52 @@
+package machine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeStacks(t *testing.T) {
+	tests := []struct {
+		name   string
+		stacks [][]string
+		want   [][]string
+	}{
+		{"sem pilhas", nil, nil},
+		{"pilha vazia", [][]string{{"?"}}, nil},
+		{"duas pilhas vazias", [][]string{{"?"}, {"?"}}, nil},
+		{"pilha sem fundo", [][]string{{}}, nil},
+		{"pilha com simbolos", [][]string{{"?", "a", "b"}}, [][]string{{"a", "b"}}},
+		{"segunda pilha vazia", [][]string{{"?", "a"}, {"?"}}, [][]string{{"a"}}},
+		{"primeira pilha vazia", [][]string{{"?"}, {"?", "b"}}, [][]string{nil, {"b"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := normalizeStacks(test.stacks)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("normalizeStacks(%v) = %v, esperado %v", test.stacks, got, test.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeStacksEqual(t *testing.T) {
+	// Configurações que o Compare deve considerar iguais
+	tests := []struct {
+		name string
+		a, b [][]string
+	}{
+		{"sem pilhas e pilha vazia", nil, [][]string{{"?"}}},
+		{"uma e duas pilhas vazias", [][]string{{"?"}}, [][]string{{"?"}, {"?"}}},
+		{"mesmo conteudo", [][]string{{"?", "a"}}, [][]string{{"?", "a"}, {"?"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, b := normalizeStacks(test.a), normalizeStacks(test.b)
+			if !reflect.DeepEqual(a, b) {
+				t.Errorf("%v e %v deveriam ser iguais, normalizadas: %v e %v", test.a, test.b, a, b)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package machine
 
 import (
 	"autosimulator/src/collections"
+	"autosimulator/src/utils"
+	"encoding/json"
 	"fmt"
 )
 
@@ -10,6 +12,15 @@ const (
 	REJECTED = "[X]"
 	RUNNING  = "[ ]"
 	INITIAL  = "[I]"
+
+	// A computação foi interrompida pelo limite de passos
+	LIMITED = "[L]"
+)
+
+const (
+	// Limite de passos usado por quem executa maquinas de terceiros. Uma
+	// maquina com um laço no fim da fita nunca para.
+	MAX_STEPS = 10000
 )
 
 const (
@@ -18,6 +29,21 @@ const (
 	TWO_STACK_MACHINE = iota
 )
 
+// Nomes dos tipos de maquina no JSON
+const (
+	SIMPLE_MACHINE_NAME    = "simple_machine"
+	ONE_STACK_MACHINE_NAME = "1_stack_machine"
+	TWO_STACK_MACHINE_NAME = "2_stack_machine"
+)
+
+// Modos de verificação do alfabeto. No modo estrito uma entrada com simbolos
+// fora do alfabeto não é executada; no modo leniente ela é executada, mas os
+// simbolos são marcados no historico da computação.
+const (
+	LENIENT_ALFABET = iota
+	STRICT_ALFABET  = iota
+)
+
 type (
 	Machine interface {
 		Type() int
@@ -31,6 +57,13 @@ type (
 		CurrentState() string
 		GetTransitions(state string) []Transition
 		GetStates() []string
+		GetAlfabet() []string
+		GetLayout() map[string]Position
+
+		// Guarda e restaura a configuração (estado atual e pilhas), para
+		// explorar as alternativas do não determinismo
+		Snapshot() Snapshot
+		Restore(s Snapshot)
 	}
 
 	Transition interface {
@@ -47,28 +80,93 @@ type (
 		FinalStates  []string          `json:"finalStates"`
 		Alfabet      []string          `json:"alfabet"`
 		Input        *collections.Fita `json:"defaultInput"`
+
+		// Posição (centro) de cada estado no diagrama. Opcional.
+		Layout map[string]Position `json:"layout,omitempty"`
+	}
+
+	Position struct {
+		X int32 `json:"x"`
+		Y int32 `json:"y"`
+	}
+
+	// Configuração da maquina. As pilhas vão da base para o topo.
+	Snapshot struct {
+		State  string
+		Stacks [][]string
 	}
 
 	Computation struct {
-		History []ComputationRecord
+		History        []ComputationRecord `json:"history"`
+		InvalidSymbols []InvalidSymbol     `json:"invalidSymbols,omitempty"`
+
+		// A execução parou no limite de passos, sem resultado
+		Limited bool `json:"limited,omitempty"`
+	}
+
+	// Opções da execução. MaxSteps 0 não limita a quantidade de passos.
+	ExecOptions struct {
+		Mode     int
+		MaxSteps int
+
+		// Não imprime o historico da computação no fim
+		Quiet bool
 	}
 
 	ComputationRecord struct {
 		lastState    string
 		currentState string
 		result       string
+
+		// Simbolo lido, transição feita e o conteudo das pilhas (da base
+		// para o topo) depois da transição
+		symbol     string
+		transition string
+		stacks     [][]string
+	}
+
+	// Simbolo da entrada que não pertence ao alfabeto da maquina.
+	// Position começa em 1, como é lido pelos alunos.
+	InvalidSymbol struct {
+		Symbol   string `json:"symbol"`
+		Position int    `json:"position"`
+	}
+
+	AlfabetError struct {
+		Symbols []InvalidSymbol
 	}
 )
 
 func Execute(m Machine, fita *collections.Fita) *Computation {
+	// No modo leniente não há erro, apenas os simbolos marcados
+	comp, _ := ExecuteWithMode(m, fita, LENIENT_ALFABET)
+	return comp
+}
+
+func ExecuteWithMode(m Machine, fita *collections.Fita, mode int) (*Computation, error) {
+	return ExecuteWithOptions(m, fita, ExecOptions{Mode: mode})
+}
+
+func ExecuteWithOptions(m Machine, fita *collections.Fita, opts ExecOptions) (*Computation, error) {
+	// Verifica o alfabeto antes de rodar a maquina
+	invalid, err := CheckInput(m, fita, opts.Mode)
+	if err != nil {
+		return nil, err
+	}
+
 	// Seta o estado inicial
 	m.Init(fita)
 
 	// Criar um registro para salvar o historico da computação
 	comp := newComputation(m)
+	comp.InvalidSymbols = invalid
 
-	var ok bool = true
 	for {
+		if opts.MaxSteps > 0 && len(comp.History)-1 >= opts.MaxSteps {
+			comp.Limited = true
+			break
+		}
+
 		// Lê o proximo input
 		symbol := fita.Read()
 
@@ -76,43 +174,106 @@ func Execute(m Machine, fita *collections.Fita) *Computation {
 		stateBefore := m.CurrentState()
 
 		// Faz a transição de estados
-		ok = NextTransition(m, symbol)
-		if !ok {
+		t := NextTransition(m, symbol)
+		if t == nil {
 			break
 		}
 
 		// Salva o histórico da transição
-		comp.add(stateBefore, m.CurrentState())
+		comp.add(m, stateBefore, symbol, t)
 	}
 
 	// Marca se foi aceita a entrada
 	comp.setResult(m)
 
 	// Printa o histórico da computação
-	fmt.Printf("Fita: %s\nResultado:\n%s\n", fita.Stringfy(), comp.Stringfy())
-	return comp
+	if !opts.Quiet {
+		fmt.Printf("Fita: %s\nResultado:\n%s\n", fita.Stringfy(), comp.Stringfy())
+	}
+
+	return comp, nil
+}
+
+// Verifica se os simbolos da entrada pertencem ao alfabeto da maquina.
+// No modo estrito retorna um *AlfabetError se algum simbolo for invalido.
+func CheckInput(m Machine, fita *collections.Fita, mode int) ([]InvalidSymbol, error) {
+	invalid := CheckAlfabet(m.GetAlfabet(), fita)
+	if len(invalid) > 0 && mode == STRICT_ALFABET {
+		return invalid, &AlfabetError{Symbols: invalid}
+	}
+
+	return invalid, nil
+}
+
+// Retorna os simbolos da fita que não estão no alfabeto. Um alfabeto vazio
+// aceita qualquer simbolo.
+func CheckAlfabet(alfabet []string, fita *collections.Fita) []InvalidSymbol {
+	if len(alfabet) == 0 {
+		return nil
+	}
+
+	var invalid []InvalidSymbol
+	symbols := fita.ToArray()
+	for i, symbol := range symbols {
+		// O ultimo simbolo é o final da fita
+		if i == len(symbols)-1 && symbol == collections.TAIL_FITA {
+			break
+		}
+
+		if !utils.Contains(alfabet, symbol) {
+			invalid = append(invalid, InvalidSymbol{Symbol: symbol, Position: i + 1})
+		}
+	}
+
+	return invalid
+}
+
+// Nome do tipo da maquina como é escrito no JSON
+func TypeName(machineType int) string {
+	switch machineType {
+	case ONE_STACK_MACHINE:
+		return ONE_STACK_MACHINE_NAME
+	case TWO_STACK_MACHINE:
+		return TWO_STACK_MACHINE_NAME
+	default:
+		return SIMPLE_MACHINE_NAME
+	}
 }
 
-func NextTransition(m Machine, symbol string) bool {
+// Quantidade de elementos das tuplas de transição de cada tipo de maquina
+func TransitionArity(machineType int) int {
+	switch machineType {
+	case ONE_STACK_MACHINE:
+		return 4
+	case TWO_STACK_MACHINE:
+		return 6
+	default:
+		return 2
+	}
+}
+
+// Faz a primeira transição possivel com o simbolo lido e a retorna.
+// Retorna nil se nenhuma transição foi feita.
+func NextTransition(m Machine, symbol string) Transition {
 	if symbol == "" {
-		return false
+		return nil
 	}
 
 	possibleTransitions := m.PossibleTransitions()
 	if possibleTransitions == nil {
-		return false
+		return nil
 	}
 
 	for _, t := range possibleTransitions {
 		if symbol == t.GetSymbol() {
 			result := t.MakeTransition(m)
 			if result {
-				return true
+				return t
 			}
 		}
 	}
 
-	return false
+	return nil
 }
 
 func newComputation(m Machine) *Computation {
@@ -127,30 +288,54 @@ func newComputationRecord(m Machine) *ComputationRecord {
 		lastState:    m.CurrentState(),
 		currentState: m.CurrentState(),
 		result:       INITIAL,
+		stacks:       stacksContent(m),
 	}
 	return &record
 }
 
 func (c *Computation) setResult(m Machine) {
-	if m.InLastState() {
+	if c.Limited {
+		c.History[len(c.History)-1].result = LIMITED
+	} else if m.InLastState() {
 		c.History[len(c.History)-1].result = ACCEPTED
 	} else {
 		c.History[len(c.History)-1].result = REJECTED
 	}
 }
 
-func (c *Computation) add(lastState string, currentState string) {
+func (c *Computation) add(m Machine, lastState, symbol string, t Transition) {
 	record := ComputationRecord{
 		lastState:    lastState,
-		currentState: currentState,
+		currentState: m.CurrentState(),
 		result:       RUNNING,
+		symbol:       symbol,
+		transition:   t.Stringfy(),
+		stacks:       stacksContent(m),
 	}
 
 	c.History = append(c.History, record)
 }
 
+func (c *Computation) Accepted() bool {
+	return c.History[len(c.History)-1].result == ACCEPTED
+}
+
+// Copia o conteudo das pilhas da maquina, da base para o topo
+func stacksContent(m Machine) [][]string {
+	var result [][]string
+	for _, stack := range m.Stacks() {
+		result = append(result, utils.Reverse(stack.Peek(stack.Length())))
+	}
+
+	return result
+}
+
 func (c *Computation) Stringfy() string {
 	var s string
+	for _, v := range c.InvalidSymbols {
+		s += fmt.Sprintf("!: %s\n", v.Stringfy())
+	}
+
 	for i, v := range c.History {
 		s += fmt.Sprintf("%d: %s\n", i, v.Stringfy())
 	}
@@ -171,5 +356,42 @@ func (cr *ComputationRecord) Details() map[string]string {
 	details["LAST_STATE"] = cr.lastState
 	details["NEXT_STATE"] = cr.currentState
 	details["RESULT"] = cr.result
+	details["SYMBOL"] = cr.symbol
+	details["TRANSITION"] = cr.transition
 	return details
 }
+
+func (cr *ComputationRecord) Stacks() [][]string {
+	return cr.stacks
+}
+
+func (cr ComputationRecord) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		LastState    string     `json:"lastState"`
+		CurrentState string     `json:"currentState"`
+		Symbol       string     `json:"symbol,omitempty"`
+		Transition   string     `json:"transition,omitempty"`
+		Stacks       [][]string `json:"stacks,omitempty"`
+		Result       string     `json:"result"`
+	}{
+		LastState:    cr.lastState,
+		CurrentState: cr.currentState,
+		Symbol:       cr.symbol,
+		Transition:   cr.transition,
+		Stacks:       cr.stacks,
+		Result:       cr.result,
+	})
+}
+
+func (is *InvalidSymbol) Stringfy() string {
+	return fmt.Sprintf("simbolo '%s' na posição %d não pertence ao alfabeto", is.Symbol, is.Position)
+}
+
+func (e *AlfabetError) Error() string {
+	s := "a entrada possui simbolos fora do alfabeto:"
+	for _, v := range e.Symbols {
+		s += fmt.Sprintf("\n\t%s", v.Stringfy())
+	}
+
+	return s
+}
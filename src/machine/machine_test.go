@@ -0,0 +1,239 @@
+package machine_test
+
+import (
+	"autosimulator/src/collections"
+	"autosimulator/src/machine"
+	"autosimulator/src/reader"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const (
+	// Aceita a^n b^n, empilhando um b para cada a
+	PDA_MACHINE = `{
+		"type": "1_stack_machine",
+		"states": ["q0", "q1", "qf"],
+		"initialState": "q0",
+		"finalStates": ["qf"],
+		"alfabet": ["a", "b"],
+		"defaultInput": ["a", "b"],
+		"transitions": {
+			"q0": ["(a, &, b, q0)", "(b, b, &, q1)"],
+			"q1": ["(b, b, &, q1)", "(?, ?, &, qf)"]
+		}
+	}`
+
+	// Aceita a*, sem usar a pilha
+	PDA_NO_STACK = `{
+		"type": "1_stack_machine",
+		"states": ["q0", "qf"],
+		"initialState": "q0",
+		"finalStates": ["qf"],
+		"alfabet": ["a"],
+		"defaultInput": ["a"],
+		"transitions": {
+			"q0": ["(a, &, &, q0)", "(?, &, &, qf)"]
+		}
+	}`
+
+	// Aceita a*, lendo o fim da fita como a PDA_NO_STACK
+	DFA_MACHINE = `{
+		"type": "simple_machine",
+		"states": ["q0", "qf"],
+		"initialState": "q0",
+		"finalStates": ["qf"],
+		"alfabet": ["a"],
+		"defaultInput": ["a"],
+		"transitions": {
+			"q0": ["(a, q0)", "(?, qf)"]
+		}
+	}`
+
+	// Fica no fim da fita para sempre
+	LOOP_MACHINE = `{
+		"type": "1_stack_machine",
+		"states": ["q0", "qf"],
+		"initialState": "q0",
+		"finalStates": ["qf"],
+		"alfabet": ["a"],
+		"defaultInput": ["a"],
+		"transitions": {
+			"q0": ["(a, &, &, q0)", "(?, &, &, q0)"]
+		}
+	}`
+)
+
+func parse(t *testing.T, content string) machine.Machine {
+	t.Helper()
+	m, err := reader.ParseMachine("teste", []byte(content))
+	if err != nil {
+		t.Fatalf("erro ao ler a maquina: %s", err)
+	}
+
+	return m
+}
+
+func TestCheckAlfabet(t *testing.T) {
+	tests := []struct {
+		name    string
+		alfabet []string
+		input   []string
+		want    []machine.InvalidSymbol
+	}{
+		{"entrada valida", []string{"a", "b"}, []string{"a", "b", "a"}, nil},
+		{"entrada vazia", []string{"a"}, nil, nil},
+		{"alfabeto vazio aceita tudo", nil, []string{"x", "y"}, nil},
+		{"um simbolo invalido", []string{"a", "b"}, []string{"a", "c", "b"}, []machine.InvalidSymbol{{Symbol: "c", Position: 2}}},
+		{"varios simbolos invalidos", []string{"a"}, []string{"x", "a", "y"}, []machine.InvalidSymbol{{Symbol: "x", Position: 1}, {Symbol: "y", Position: 3}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := machine.CheckAlfabet(test.alfabet, collections.FitaFromArray(test.input))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("CheckAlfabet(%v, %v) = %v, esperado %v", test.alfabet, test.input, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckInput(t *testing.T) {
+	m := parse(t, PDA_MACHINE)
+	tests := []struct {
+		name    string
+		input   []string
+		mode    int
+		invalid int
+		err     bool
+	}{
+		{"valida estrito", []string{"a", "b"}, machine.STRICT_ALFABET, 0, false},
+		{"valida leniente", []string{"a", "b"}, machine.LENIENT_ALFABET, 0, false},
+		{"invalida estrito", []string{"a", "c"}, machine.STRICT_ALFABET, 1, true},
+		{"invalida leniente", []string{"a", "c"}, machine.LENIENT_ALFABET, 1, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			invalid, err := machine.CheckInput(m, collections.FitaFromArray(test.input), test.mode)
+			if len(invalid) != test.invalid {
+				t.Errorf("esperado %d simbolos invalidos, veio %v", test.invalid, invalid)
+			}
+
+			var alfabetErr *machine.AlfabetError
+			if test.err != errors.As(err, &alfabetErr) {
+				t.Errorf("erro inesperado: %v", err)
+			}
+		})
+	}
+}
+
+func TestExecuteWithOptionsStrict(t *testing.T) {
+	m := parse(t, PDA_MACHINE)
+	fita := collections.FitaFromArray([]string{"a", "c"})
+
+	comp, err := machine.ExecuteWithOptions(m, fita, machine.ExecOptions{Mode: machine.STRICT_ALFABET, Quiet: true})
+	if err == nil || comp != nil {
+		t.Fatalf("a entrada fora do alfabeto não deveria executar no modo estrito")
+	}
+
+	fita.Reset()
+	comp, err = machine.ExecuteWithOptions(m, fita, machine.ExecOptions{Mode: machine.LENIENT_ALFABET, Quiet: true})
+	if err != nil {
+		t.Fatalf("erro no modo leniente: %s", err)
+	}
+
+	want := []machine.InvalidSymbol{{Symbol: "c", Position: 2}}
+	if !reflect.DeepEqual(comp.InvalidSymbols, want) {
+		t.Errorf("simbolos invalidos = %v, esperado %v", comp.InvalidSymbols, want)
+	}
+}
+
+func TestExecuteWithOptionsMaxSteps(t *testing.T) {
+	tests := []struct {
+		name     string
+		machine  string
+		input    []string
+		maxSteps int
+		limited  bool
+		result   string
+	}{
+		{"laço no fim da fita", LOOP_MACHINE, []string{"a", "a"}, 50, true, machine.LIMITED},
+		{"laço sem entrada", LOOP_MACHINE, nil, 1, true, machine.LIMITED},
+		{"para antes do limite", PDA_MACHINE, []string{"a", "b"}, 50, false, machine.ACCEPTED},
+		{"rejeita antes do limite", PDA_MACHINE, []string{"a", "a", "b"}, 50, false, machine.REJECTED},
+		{"para um passo antes do limite", PDA_MACHINE, []string{"a", "b"}, 4, false, machine.ACCEPTED},
+		{"cortada pelo limite", PDA_MACHINE, []string{"a", "a", "b", "b"}, 2, true, machine.LIMITED},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := parse(t, test.machine)
+			opts := machine.ExecOptions{Mode: machine.STRICT_ALFABET, MaxSteps: test.maxSteps, Quiet: true}
+			comp, err := machine.ExecuteWithOptions(m, collections.FitaFromArray(test.input), opts)
+			if err != nil {
+				t.Fatalf("erro na execução: %s", err)
+			}
+
+			if comp.Limited != test.limited {
+				t.Errorf("Limited = %v, esperado %v", comp.Limited, test.limited)
+			}
+
+			steps := len(comp.History) - 1
+			if steps > test.maxSteps {
+				t.Errorf("%d passos, acima do limite de %d", steps, test.maxSteps)
+			}
+
+			if test.limited && steps != test.maxSteps {
+				t.Errorf("%d passos, esperado parar em %d", steps, test.maxSteps)
+			}
+
+			last := comp.History[len(comp.History)-1]
+			if result := last.Details()["RESULT"]; result != test.result {
+				t.Errorf("resultado = %q, esperado %q", result, test.result)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name   string
+		a, b   string
+		input  []string
+		reason int
+		equal  bool
+	}{
+		// A pilha vazia da maquina de pilha é igual à falta de pilhas
+		{"afd e pilha sem uso", DFA_MACHINE, PDA_NO_STACK, []string{"a", "a"}, 0, true},
+		{"mesma maquina", PDA_MACHINE, PDA_MACHINE, []string{"a", "b"}, 0, true},
+		{"pilhas diferentes", PDA_NO_STACK, PDA_MACHINE, []string{"a"}, machine.DIVERGE_STACKS, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := machine.ExecOptions{Mode: machine.LENIENT_ALFABET, MaxSteps: machine.MAX_STEPS, Quiet: true}
+			a, err := machine.ExecuteWithOptions(parse(t, test.a), collections.FitaFromArray(test.input), opts)
+			if err != nil {
+				t.Fatalf("erro na execução: %s", err)
+			}
+
+			b, err := machine.ExecuteWithOptions(parse(t, test.b), collections.FitaFromArray(test.input), opts)
+			if err != nil {
+				t.Fatalf("erro na execução: %s", err)
+			}
+
+			divergence := machine.Compare(a, b)
+			if test.equal {
+				if divergence != nil {
+					t.Errorf("não deveriam divergir: %s", divergence.Stringfy())
+				}
+				return
+			}
+
+			if divergence == nil || divergence.Reason != test.reason {
+				t.Errorf("divergencia = %v, esperado motivo %d", divergence, test.reason)
+			}
+		})
+	}
+}
@@ -90,6 +90,26 @@ func (m *Machine) GetInput() *collections.Fita {
 	return m.Input
 }
 
+func (m *Machine) GetAlfabet() []string {
+	return m.Alfabet
+}
+
+func (m *Machine) GetLayout() map[string]machine.Position {
+	return m.Layout
+}
+
+func (m *Machine) Snapshot() machine.Snapshot {
+	return machine.Snapshot{
+		State:  m.currentState,
+		Stacks: [][]string{utils.Reverse(m.stack.Peek(m.stack.Length()))},
+	}
+}
+
+func (m *Machine) Restore(s machine.Snapshot) {
+	m.currentState = s.State
+	m.stack = collections.StackFromArray(s.Stacks[0])
+}
+
 func (t *Transition) MakeTransition(m machine.Machine) bool {
 	stackMachine, ok := m.(*Machine)
 	if !ok {
@@ -143,16 +163,11 @@ func (t *Transition) GetResultState() string {
 }
 
 func (t *Transition) UnmarshalJSON(data []byte) error {
-	parsed, err := utils.ParseTransition((string(data)))
+	parsed, err := utils.ParseTransition(string(data), 4)
 	if err != nil {
 		return err
 	}
 
-	if len(parsed) > 4 {
-		err = fmt.Errorf("transição mal formada: %v", parsed)
-		return err
-	}
-
 	*t = Transition{
 		Symbol:      parsed[0],
 		Read:        parsed[1],
@@ -0,0 +1,178 @@
+package machine
+
+import (
+	"autosimulator/src/collections"
+)
+
+// Arvore de computação: diferente do Execute, que segue sempre a primeira
+// transição possivel, explora todas as transições aplicaveis em cada
+// configuração. O primeiro filho de cada nó é o caminho seguido pelo
+// Execute.
+
+// Situação de cada ramo da arvore
+const (
+	BRANCH_RUNNING  = iota
+	BRANCH_ACCEPTED = iota
+	BRANCH_REJECTED = iota
+	BRANCH_DEAD     = iota
+	BRANCH_LIMIT    = iota
+)
+
+const (
+	// Limites da exploração, para maquinas com laços infinitos (como as
+	// transições de pilha lendo o fim da fita)
+	TREE_MAX_DEPTH = 100
+	TREE_MAX_NODES = 5000
+)
+
+type ComputationNode struct {
+	Record ComputationRecord `json:"record"`
+
+	// BRANCH_RUNNING para os nós internos. As folhas são aceitas, rejeitadas
+	// (a entrada acabou fora de um estado final), mortas (não havia
+	// transição antes do fim da entrada) ou cortadas pelos limites.
+	Status   int                `json:"status"`
+	Depth    int                `json:"depth"`
+	Children []*ComputationNode `json:"children,omitempty"`
+	Parent   *ComputationNode   `json:"-"`
+}
+
+type treeExplorer struct {
+	m        Machine
+	fita     *collections.Fita
+	maxDepth int
+	nodes    int
+}
+
+// Explora todos os ramos da computação até a profundidade maxDepth (0 usa
+// TREE_MAX_DEPTH)
+func ExecuteTree(m Machine, fita *collections.Fita, maxDepth int) *ComputationNode {
+	if maxDepth <= 0 {
+		maxDepth = TREE_MAX_DEPTH
+	}
+
+	m.Init(fita)
+	root := &ComputationNode{Record: *newComputationRecord(m)}
+	explorer := &treeExplorer{m: m, fita: fita, maxDepth: maxDepth, nodes: 1}
+	explorer.explore(root)
+
+	// O nó inicial sozinho também é uma folha
+	if len(root.Children) == 0 {
+		root.Record.result = resultOf(root.Status)
+	}
+
+	fita.Reset()
+	return root
+}
+
+func (e *treeExplorer) explore(node *ComputationNode) {
+	snapshot := e.m.Snapshot()
+	position := e.fita.Position()
+	symbol := e.fita.Read()
+
+	if node.Depth >= e.maxDepth {
+		node.Status = BRANCH_LIMIT
+		return
+	}
+
+	for _, t := range e.m.PossibleTransitions() {
+		if t.GetSymbol() != symbol {
+			continue
+		}
+
+		if e.nodes >= TREE_MAX_NODES {
+			node.Status = BRANCH_LIMIT
+			return
+		}
+
+		e.m.Restore(snapshot)
+		e.fita.Seek(position + 1)
+		if !t.MakeTransition(e.m) {
+			continue
+		}
+
+		child := &ComputationNode{
+			Record: ComputationRecord{
+				lastState:    snapshot.State,
+				currentState: e.m.CurrentState(),
+				result:       RUNNING,
+				symbol:       symbol,
+				transition:   t.Stringfy(),
+				stacks:       stacksContent(e.m),
+			},
+			Depth:  node.Depth + 1,
+			Parent: node,
+		}
+
+		e.nodes++
+		node.Children = append(node.Children, child)
+		e.explore(child)
+		if len(child.Children) == 0 {
+			child.Record.result = resultOf(child.Status)
+		}
+	}
+
+	if len(node.Children) > 0 {
+		node.Status = BRANCH_RUNNING
+		return
+	}
+
+	// Nenhuma transição: a maquina para nesta configuração, com o simbolo
+	// já lido, como no Execute
+	e.m.Restore(snapshot)
+	e.fita.Seek(position + 1)
+	switch {
+	case e.m.InLastState():
+		node.Status = BRANCH_ACCEPTED
+	case e.fita.IsLast():
+		node.Status = BRANCH_REJECTED
+	default:
+		node.Status = BRANCH_DEAD
+	}
+}
+
+func resultOf(status int) string {
+	switch status {
+	case BRANCH_ACCEPTED:
+		return ACCEPTED
+	case BRANCH_RUNNING, BRANCH_LIMIT:
+		return RUNNING
+	default:
+		return REJECTED
+	}
+}
+
+// Computação linear da raiz até este nó, para ser reproduzida passo a passo
+func (n *ComputationNode) Computation() *Computation {
+	var path []ComputationRecord
+	for node := n; node != nil; node = node.Parent {
+		path = append([]ComputationRecord{node.Record}, path...)
+	}
+
+	return &Computation{History: path}
+}
+
+// Folhas da arvore, da esquerda para a direita
+func (n *ComputationNode) Leaves() []*ComputationNode {
+	if len(n.Children) == 0 {
+		return []*ComputationNode{n}
+	}
+
+	var leaves []*ComputationNode
+	for _, child := range n.Children {
+		leaves = append(leaves, child.Leaves()...)
+	}
+
+	return leaves
+}
+
+// Algum ramo da arvore foi aceito
+func (n *ComputationNode) Accepted() bool {
+	for _, leaf := range n.Leaves() {
+		if leaf.Status == BRANCH_ACCEPTED {
+			return true
+		}
+	}
+
+	return false
+}
@@ -79,6 +79,14 @@ func (m *Machine) GetInput() *collections.Fita {
 	return m.Input
 }
 
+func (m *Machine) GetAlfabet() []string {
+	return m.Alfabet
+}
+
+func (m *Machine) GetLayout() map[string]machine.Position {
+	return m.Layout
+}
+
 func (m *Machine) GetTransitions(state string) []machine.Transition {
 	transitions := m.Transitions[state]
 	result := make([]machine.Transition, len(transitions))
@@ -100,6 +108,22 @@ func (m *Machine) Stacks() []*collections.Stack {
 	return []*collections.Stack{m.stackA, m.stackB}
 }
 
+func (m *Machine) Snapshot() machine.Snapshot {
+	return machine.Snapshot{
+		State: m.currentState,
+		Stacks: [][]string{
+			utils.Reverse(m.stackA.Peek(m.stackA.Length())),
+			utils.Reverse(m.stackB.Peek(m.stackB.Length())),
+		},
+	}
+}
+
+func (m *Machine) Restore(s machine.Snapshot) {
+	m.currentState = s.State
+	m.stackA = collections.StackFromArray(s.Stacks[0])
+	m.stackB = collections.StackFromArray(s.Stacks[1])
+}
+
 func (t *Transition) MakeTransition(m machine.Machine) bool {
 	stackMachine, ok := m.(*Machine)
 	if !ok {
@@ -183,16 +207,11 @@ func (t *Transition) GetResultState() string {
 }
 
 func (t *Transition) UnmarshalJSON(data []byte) error {
-	parsed, err := utils.ParseTransition((string(data)))
+	parsed, err := utils.ParseTransition(string(data), 6)
 	if err != nil {
 		return err
 	}
 
-	if len(parsed) > 6 {
-		err = fmt.Errorf("transição mal formada: %v", parsed)
-		return err
-	}
-
 	*t = Transition{
 		Symbol:      parsed[0],
 		ReadA:       parsed[1],
@@ -24,24 +24,30 @@ func ReadMachine(path string) (machine.Machine, error) {
 		return nil, err
 	}
 
+	return ParseMachine(path, content)
+}
+
+// Lê a maquina a partir do conteudo JSON. O nome é usado apenas nas
+// mensagens de erro.
+func ParseMachine(name string, content []byte) (machine.Machine, error) {
 	var m *machine.BaseMachine
-	err = json.Unmarshal(content, &m)
+	err := json.Unmarshal(content, &m)
 	if err != nil {
-		return nil, err
+		return nil, unmarshalError(name, err)
 	}
 
-	if m.Input == nil {
-		return nil, fmt.Errorf("syntax error. Não há input default. Maquina: ", string(content))
+	if m == nil || m.Input == nil {
+		return nil, fmt.Errorf("syntax error. Não há input default. Maquina: %s", string(content))
 	}
 
 	var readedMachine machine.Machine
 	switch m.Type {
-	case "simple_machine":
-		readedMachine, err = ReadSimpleMachine(path)
-	case "1_stack_machine":
-		readedMachine, err = ReadOneStackMachine(path)
-	case "2_stack_machine":
-		readedMachine, err = ReadTwoStackMachine(path)
+	case machine.SIMPLE_MACHINE_NAME:
+		readedMachine, err = parseSimpleMachine(name, content)
+	case machine.ONE_STACK_MACHINE_NAME:
+		readedMachine, err = parseOneStackMachine(name, content)
+	case machine.TWO_STACK_MACHINE_NAME:
+		readedMachine, err = parseTwoStackMachine(name, content)
 	default:
 		readedMachine, err = nil, fmt.Errorf("tipo de maquina não suportado: %s", m.Type)
 	}
@@ -92,8 +98,13 @@ func ReadInput(path string) (*collections.Fita, error) {
 		return nil, err
 	}
 
+	// Um editor pode deixar o arquivo vazio por um instante ao salvar
+	if len(input) == 0 {
+		return nil, fmt.Errorf("o arquivo de entrada esta vazio: %s", path)
+	}
+
 	if len(input) > 1 {
-		return nil, fmt.Errorf("o input deve possuir apenas uma linha e seus elementos devem estar separados por vírgula e sem espaço entre eles. Input:", input)
+		return nil, fmt.Errorf("o input deve possuir apenas uma linha e seus elementos devem estar separados por vírgula e sem espaço entre eles. Input: %v", input)
 	}
 
 	fmt.Println(input)
@@ -101,45 +112,57 @@ func ReadInput(path string) (*collections.Fita, error) {
 }
 
 func ReadSimpleMachine(path string) (*afdMachine.Machine, error) {
-	m := afdMachine.New()
 	content, err := readFileContent(path)
 	if err != nil {
 		return nil, err
 	}
 
-	err = json.Unmarshal(content, &m)
+	return parseSimpleMachine(path, content)
+}
+
+func ReadTwoStackMachine(path string) (*twoStackMachine.Machine, error) {
+	content, err := readFileContent(path)
 	if err != nil {
-		return nil, unmarshalError(path, err)
+		return nil, err
 	}
 
-	return m, nil
+	return parseTwoStackMachine(path, content)
 }
 
-func ReadTwoStackMachine(path string) (*twoStackMachine.Machine, error) {
-	m := twoStackMachine.New()
+func ReadOneStackMachine(path string) (*oneStackMachine.Machine, error) {
 	content, err := readFileContent(path)
 	if err != nil {
 		return nil, err
 	}
 
-	err = json.Unmarshal(content, &m)
+	return parseOneStackMachine(path, content)
+}
+
+func parseSimpleMachine(name string, content []byte) (*afdMachine.Machine, error) {
+	m := afdMachine.New()
+	err := json.Unmarshal(content, &m)
 	if err != nil {
-		return nil, unmarshalError(path, err)
+		return nil, unmarshalError(name, err)
 	}
 
 	return m, nil
 }
 
-func ReadOneStackMachine(path string) (*oneStackMachine.Machine, error) {
-	m := oneStackMachine.New()
-	content, err := readFileContent(path)
+func parseTwoStackMachine(name string, content []byte) (*twoStackMachine.Machine, error) {
+	m := twoStackMachine.New()
+	err := json.Unmarshal(content, &m)
 	if err != nil {
-		return nil, err
+		return nil, unmarshalError(name, err)
 	}
 
-	err = json.Unmarshal(content, &m)
+	return m, nil
+}
+
+func parseOneStackMachine(name string, content []byte) (*oneStackMachine.Machine, error) {
+	m := oneStackMachine.New()
+	err := json.Unmarshal(content, &m)
 	if err != nil {
-		return nil, unmarshalError(path, err)
+		return nil, unmarshalError(name, err)
 	}
 
 	return m, nil
@@ -235,6 +258,28 @@ func GetJsonList(path string) ([]string, error) {
 	return result, nil
 }
 
+// Subdiretorios e arquivos com a extensão ext (ex.: ".json") de path, em
+// ordem alfabetica. Diretorios ocultos são ignorados.
+func GetDirList(path, ext string) ([]string, []string, error) {
+	entries, err := readDir(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var dirs, files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case entry.IsDir() && !strings.HasPrefix(name, "."):
+			dirs = append(dirs, name)
+		case !entry.IsDir() && strings.EqualFold(filepath.Ext(name), ext):
+			files = append(files, name)
+		}
+	}
+
+	return dirs, files, nil
+}
+
 func isJsonExt(fileName string) bool {
 	return strings.ToLower(fileName[len(fileName)-5:]) == ".json"
 }
@@ -0,0 +1,188 @@
+package reader
+
+import (
+	"autosimulator/src/collections"
+	"autosimulator/src/machine"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Representação de uma maquina no mesmo formato do JSON lido pelo
+// ReadMachine. As transições são as tuplas em texto, ex: "(a, q1)".
+type MachineDefinition struct {
+	Type         string              `json:"type"`
+	States       []string            `json:"states"`
+	InitialState string              `json:"initialState"`
+	FinalStates  []string            `json:"finalStates"`
+	Alfabet      []string            `json:"alfabet"`
+	DefaultInput []string            `json:"defaultInput"`
+	Transitions  map[string][]string `json:"transitions"`
+
+	Layout map[string]machine.Position `json:"layout,omitempty"`
+}
+
+func DefinitionOf(m machine.Machine) *MachineDefinition {
+	def := &MachineDefinition{
+		Type:         machine.TypeName(m.Type()),
+		States:       append([]string{}, m.GetStates()...),
+		InitialState: m.GetInitialState(),
+		FinalStates:  append([]string{}, m.GetFinalStates()...),
+		Alfabet:      append([]string{}, m.GetAlfabet()...),
+		DefaultInput: InputSymbols(m.GetInput()),
+		Transitions:  make(map[string][]string),
+		Layout:       make(map[string]machine.Position),
+	}
+
+	for state, position := range m.GetLayout() {
+		def.Layout[state] = position
+	}
+
+	for _, state := range m.GetStates() {
+		for _, t := range m.GetTransitions(state) {
+			def.Transitions[state] = append(def.Transitions[state], t.Stringfy())
+		}
+	}
+
+	return def
+}
+
+// Simbolos da fita, sem o fim da fita
+func InputSymbols(input *collections.Fita) []string {
+	symbols := []string{}
+	if input == nil {
+		return symbols
+	}
+
+	for _, symbol := range input.ToArray() {
+		if symbol == collections.TAIL_FITA {
+			break
+		}
+
+		symbols = append(symbols, symbol)
+	}
+
+	return symbols
+}
+
+// JSON com a mesma indentação dos arquivos em machines/. O escape de HTML é
+// desligado para manter o "&" da palavra vazia legivel.
+func (def *MachineDefinition) Marshal() ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "   ")
+	err := encoder.Encode(def)
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// Valida a definição lendo-a como uma maquina
+func (def *MachineDefinition) Machine(name string) (machine.Machine, error) {
+	content, err := def.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseMachine(name, content)
+}
+
+// Salva as posições dos estados no arquivo da maquina. Só a chave "layout"
+// é trocada: as demais continuam como estão no arquivo, na mesma ordem.
+func WriteLayout(path string, layout map[string]machine.Position) error {
+	content, err := readFileContent(path)
+	if err != nil {
+		return err
+	}
+
+	keys, values, err := objectEntries(content)
+	if err != nil {
+		return unmarshalError(path, err)
+	}
+
+	encoded, err := json.Marshal(layout)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := values["layout"]; !ok {
+		keys = append(keys, "layout")
+	}
+
+	values["layout"] = encoded
+
+	var buffer bytes.Buffer
+	buffer.WriteString("{")
+	for i, key := range keys {
+		if i > 0 {
+			buffer.WriteString(",")
+		}
+
+		name, _ := json.Marshal(key)
+		buffer.Write(name)
+		buffer.WriteString(":")
+		buffer.Write(values[key])
+	}
+
+	buffer.WriteString("}")
+
+	var indented bytes.Buffer
+	err = json.Indent(&indented, buffer.Bytes(), "", "   ")
+	if err != nil {
+		return err
+	}
+
+	indented.WriteString("\n")
+	return os.WriteFile(path, indented.Bytes(), 0o644)
+}
+
+// Chaves de um objeto JSON, na ordem do arquivo, e o valor de cada uma sem
+// decodificar
+func objectEntries(content []byte) ([]string, map[string]json.RawMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if token != json.Delim('{') {
+		return nil, nil, fmt.Errorf("a maquina não é um objeto JSON")
+	}
+
+	keys := []string{}
+	values := make(map[string]json.RawMessage)
+	for decoder.More() {
+		token, err = decoder.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		key := token.(string)
+		var value json.RawMessage
+		err = decoder.Decode(&value)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+
+		values[key] = value
+	}
+
+	return keys, values, nil
+}
+
+func WriteMachine(def *MachineDefinition, path string) error {
+	content, err := def.Marshal()
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, content, 0o644)
+}
@@ -0,0 +1,327 @@
+package render
+
+import (
+	"autosimulator/src/layout"
+	"autosimulator/src/machine"
+	"autosimulator/src/theme"
+	"autosimulator/src/utils"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Desenha o diagrama da maquina e os paineis da fita, pilhas e historico sem
+// depender de uma janela do SDL. As medidas e cores são as mesmas da
+// interface grafica (src/graphics).
+
+const (
+	WIDTH, HEIGHT = 580, 750
+
+	WIDTH_REC           = 50
+	TAMANHO_ESTRUTURAS  = 9
+	DIMENSAO_ESTRUTURAS = 32
+	PADX, PADY          = 5, 5
+	FONT_SIZE           = 18
+	LABEL_SIZE          = 12
+	THICKNESS           = 2
+)
+
+// Cores do desenho, trocadas pelo SetTheme. Começam com o tema escuro, como
+// na interface grafica.
+var (
+	COLOR_BACKGROUD color
+	COLOR_DEFAULT   color
+	COLOR_INITIAL   color
+	COLOR_CURRENT   color
+	COLOR_ACCEPTED  color
+	COLOR_REJECTED  color
+)
+
+func init() {
+	dark, _ := theme.Load(theme.THEME_DARK)
+	SetTheme(dark)
+}
+
+// Troca as cores do desenho pelas do tema, as mesmas da interface grafica
+func SetTheme(t theme.Theme) error {
+	colors, err := t.Colors()
+	if err != nil {
+		return err
+	}
+
+	rgb := make([]color, len(colors))
+	for i, c := range colors {
+		rgb[i] = color{c.R, c.G, c.B}
+	}
+
+	COLOR_BACKGROUD = rgb[0]
+	COLOR_DEFAULT = rgb[1]
+	COLOR_INITIAL = rgb[2]
+	COLOR_CURRENT = rgb[3]
+	COLOR_ACCEPTED = rgb[4]
+	COLOR_REJECTED = rgb[5]
+	return nil
+}
+
+type point struct {
+	X, Y int32
+}
+
+// Renderiza o passo step da computação (-1 para o ultimo) em SVG
+func SVG(w io.Writer, m machine.Machine, comp *machine.Computation, step int) error {
+	if step < 0 || step >= len(comp.History) {
+		step = len(comp.History) - 1
+	}
+
+	c := newCanvas(WIDTH, HEIGHT, COLOR_BACKGROUD)
+	record := comp.History[step]
+	positions := statesPositions(m)
+
+	drawEdges(c, m, positions, record.Details())
+	drawStates(c, m, positions, record.Details())
+	drawFita(c, m, step, record.Details())
+	if m.Type() != machine.SIMPLE_MACHINE {
+		drawStacks(c, record.Stacks())
+	}
+
+	drawHist(c, comp, step)
+	return c.writeTo(w)
+}
+
+// Mesmo layout da interface grafica: a posição salva no arquivo da maquina
+// ou o layout automatico
+func statesPositions(m machine.Machine) map[string]point {
+	bounds := layout.Bounds{
+		X: PADX,
+		Y: PADY + WIDTH_REC/2,
+		W: WIDTH - PADX*2,
+		H: HEIGHT - (DIMENSAO_ESTRUTURAS*TAMANHO_ESTRUTURAS + PADY*2) - WIDTH_REC/2,
+	}
+
+	positions := layout.Layered(m.GetStates(), m.GetInitialState(), layout.Edges(m), bounds, WIDTH_REC)
+	for state, position := range m.GetLayout() {
+		positions[state] = position
+	}
+
+	result := make(map[string]point)
+	for state, position := range positions {
+		result[state] = point{X: position.X, Y: position.Y}
+	}
+
+	return result
+}
+
+// Mesmas cores do colorRecord da interface grafica
+func stateColor(details map[string]string) color {
+	switch details["RESULT"] {
+	case machine.INITIAL:
+		return COLOR_INITIAL
+	case machine.ACCEPTED:
+		return COLOR_ACCEPTED
+	case machine.REJECTED:
+		return COLOR_REJECTED
+	default:
+		return COLOR_CURRENT
+	}
+}
+
+func drawStates(c *canvas, m machine.Machine, positions map[string]point, details map[string]string) {
+	radius := int32(WIDTH_REC / 2)
+	for _, state := range m.GetStates() {
+		p := positions[state]
+		stroke := COLOR_DEFAULT
+		if state == details["NEXT_STATE"] {
+			stroke = stateColor(details)
+		}
+
+		c.circle(p.X, p.Y, radius, stroke, THICKNESS)
+
+		// Estados finais recebem um segundo anel
+		if utils.Contains(m.GetFinalStates(), state) {
+			c.circle(p.X, p.Y, radius-PADX, stroke, THICKNESS)
+		}
+
+		// Seta de entrada no estado inicial
+		if state == m.GetInitialState() {
+			from := point{X: p.X - radius*2, Y: p.Y}
+			to := point{X: p.X - radius, Y: p.Y}
+			c.line(from.X, from.Y, to.X, to.Y, stroke, THICKNESS)
+			drawArrowHead(c, from, to, stroke)
+		}
+
+		c.text(p.X, p.Y, state, FONT_SIZE, "middle", COLOR_DEFAULT)
+	}
+}
+
+func drawEdges(c *canvas, m machine.Machine, positions map[string]point, details map[string]string) {
+	radius := float64(WIDTH_REC / 2)
+	labels := layout.Labels(m)
+	for _, state := range m.GetStates() {
+		for _, next := range m.GetStates() {
+			transitions, ok := labels[state][next]
+			if !ok {
+				continue
+			}
+
+			// A ultima transição feita recebe a cor do estado destino
+			stroke := COLOR_DEFAULT
+			var thickness int32 = THICKNESS
+			highlight := ""
+			if details["TRANSITION"] != "" && details["LAST_STATE"] == state && details["NEXT_STATE"] == next {
+				stroke = stateColor(details)
+				thickness *= 2
+				highlight = layout.LabelOf(details["TRANSITION"])
+			}
+
+			from, to := position(positions[state]), position(positions[next])
+			var curve layout.Curve
+			switch {
+			case state == next:
+				curve = layout.SelfLoop(from, radius)
+			case len(labels[next][state]) > 0:
+				// Arestas nos dois sentidos são curvadas para não se sobreporem
+				curve = layout.EdgeCurve(from, to, radius, radius)
+			default:
+				curve = layout.EdgeCurve(from, to, radius, 0)
+			}
+
+			c.path(fmt.Sprintf("M %d %d Q %d %d, %d %d",
+				curve.Start.X, curve.Start.Y,
+				curve.Control.X, curve.Control.Y,
+				curve.End.X, curve.End.Y), stroke, thickness)
+
+			tip, left, right := curve.ArrowHead(DIMENSAO_ESTRUTURAS / 3)
+			c.polygon([]point{point(tip), point(left), point(right)}, stroke)
+
+			lines, align := curve.LabelLines(PADX, len(transitions), LABEL_SIZE+2)
+			for i, label := range transitions {
+				fill := COLOR_DEFAULT
+				if label == highlight {
+					fill = stroke
+					drawLabelBox(c, lines[i], label, align, stroke)
+				}
+
+				c.text(lines[i].X, lines[i].Y, label, LABEL_SIZE, textAnchor(align), fill)
+			}
+		}
+	}
+}
+
+// Caixa em volta do rotulo da transição feita. A largura é estimada pela
+// fonte monoespaçada.
+func drawLabelBox(c *canvas, anchor machine.Position, label string, align int, stroke color) {
+	width := int32(len([]rune(label)))*LABEL_SIZE*3/5 + PADX
+	height := int32(LABEL_SIZE + 2)
+	x := anchor.X - width/2
+	switch align {
+	case layout.ALIGN_START:
+		x = anchor.X - PADX/2
+	case layout.ALIGN_END:
+		x = anchor.X - width + PADX/2
+	}
+
+	c.rect(x, anchor.Y-height/2, width, height, stroke, COLOR_BACKGROUD, 1)
+}
+
+func textAnchor(align int) string {
+	switch align {
+	case layout.ALIGN_START:
+		return "start"
+	case layout.ALIGN_END:
+		return "end"
+	default:
+		return "middle"
+	}
+}
+
+func position(p point) machine.Position {
+	return machine.Position{X: p.X, Y: p.Y}
+}
+
+func drawArrowHead(c *canvas, from, to point, fill color) {
+	size := float64(DIMENSAO_ESTRUTURAS / 3)
+	dx := float64(to.X - from.X)
+	dy := float64(to.Y - from.Y)
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return
+	}
+
+	ux, uy := dx/length, dy/length
+	base := point{X: to.X - int32(ux*size), Y: to.Y - int32(uy*size)}
+	left := point{X: base.X - int32(uy*size/2), Y: base.Y + int32(ux*size/2)}
+	right := point{X: base.X + int32(uy*size/2), Y: base.Y - int32(ux*size/2)}
+	c.polygon([]point{to, left, right}, fill)
+}
+
+func drawFita(c *canvas, m machine.Machine, step int, details map[string]string) {
+	var cell int32 = DIMENSAO_ESTRUTURAS
+	x := WIDTH - PADX*5 - (cell * (TAMANHO_ESTRUTURAS + 8))
+	y := HEIGHT - cell - PADY
+
+	for i := int32(0); i < TAMANHO_ESTRUTURAS; i++ {
+		c.rect(x+cell*i, y, cell, cell, COLOR_DEFAULT, COLOR_BACKGROUD, THICKNESS)
+	}
+
+	// A primeira celula mostra o simbolo consumido pela ultima transição
+	if details["TRANSITION"] != "" {
+		c.rect(x, y, cell, cell, stateColor(details), COLOR_BACKGROUD, THICKNESS*2)
+		c.text(x+cell/2, y+cell/2, details["SYMBOL"], FONT_SIZE, "middle", COLOR_DEFAULT)
+	}
+
+	// Cabeça da fita, sobre o proximo simbolo a ser lido
+	head := point{X: x + cell + cell/2, Y: y - PADY}
+	c.polygon([]point{head, {X: head.X - cell/4, Y: head.Y - cell/4}, {X: head.X + cell/4, Y: head.Y - cell/4}}, COLOR_DEFAULT)
+
+	// Cada passo consome um simbolo da fita
+	buffer := utils.AjustMaxLen(m.GetInput().ToArray(), step, TAMANHO_ESTRUTURAS-1)
+	for i, symbol := range buffer {
+		c.text(x+cell*int32(i+1)+cell/2, y+cell/2, symbol, FONT_SIZE, "middle", COLOR_DEFAULT)
+	}
+}
+
+func drawStacks(c *canvas, stacks [][]string) {
+	var cell int32 = DIMENSAO_ESTRUTURAS
+	for i, stack := range stacks {
+		x := WIDTH - (PADX+cell)*int32(i+1)
+		y := HEIGHT - (PADY + cell*TAMANHO_ESTRUTURAS)
+		for j := int32(0); j < TAMANHO_ESTRUTURAS; j++ {
+			c.rect(x, y+cell*j, cell, cell, COLOR_DEFAULT, COLOR_BACKGROUD, THICKNESS)
+		}
+
+		if len(stack) > TAMANHO_ESTRUTURAS {
+			stack = stack[len(stack)-TAMANHO_ESTRUTURAS:]
+		}
+
+		// Da base (embaixo) para o topo
+		bottom := y + cell*(TAMANHO_ESTRUTURAS-1) + cell/2
+		for j, symbol := range stack {
+			c.text(x+cell/2, bottom-cell*int32(j), symbol, FONT_SIZE, "middle", COLOR_DEFAULT)
+		}
+	}
+}
+
+func drawHist(c *canvas, comp *machine.Computation, step int) {
+	var amount int32 = 3
+	var width int32 = DIMENSAO_ESTRUTURAS * 6
+	x := WIDTH - (DIMENSAO_ESTRUTURAS*2 + PADX*3 + width)
+	y := HEIGHT - PADY - DIMENSAO_ESTRUTURAS*amount
+
+	lines := []string{"---", comp.History[step].Stringfy(), "---"}
+	if step > 0 {
+		lines[0] = comp.History[step-1].Stringfy()
+	}
+
+	if step < len(comp.History)-1 {
+		lines[2] = comp.History[step+1].Stringfy()
+	}
+
+	for i := int32(0); i < amount; i++ {
+		c.rect(x, y+DIMENSAO_ESTRUTURAS*i, width, DIMENSAO_ESTRUTURAS, COLOR_DEFAULT, COLOR_BACKGROUD, THICKNESS)
+		c.text(x+PADX, y+DIMENSAO_ESTRUTURAS*i+DIMENSAO_ESTRUTURAS/2, lines[i], FONT_SIZE*3/4, "start", COLOR_DEFAULT)
+	}
+
+	// Seta apontando para o passo atual
+	arrow := point{X: x - PADX, Y: y + DIMENSAO_ESTRUTURAS + DIMENSAO_ESTRUTURAS/2}
+	c.polygon([]point{arrow, {X: arrow.X - DIMENSAO_ESTRUTURAS/4, Y: arrow.Y - DIMENSAO_ESTRUTURAS/4}, {X: arrow.X - DIMENSAO_ESTRUTURAS/4, Y: arrow.Y + DIMENSAO_ESTRUTURAS/4}}, COLOR_DEFAULT)
+}
@@ -0,0 +1,72 @@
+package render
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+type (
+	color struct {
+		R, G, B uint8
+	}
+
+	// Acumula os elementos de uma imagem SVG
+	canvas struct {
+		width, height int32
+		body          strings.Builder
+	}
+)
+
+func (c color) hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func newCanvas(width, height int32, background color) *canvas {
+	c := &canvas{width: width, height: height}
+	c.rect(0, 0, width, height, background, background, 0)
+	return c
+}
+
+func (c *canvas) rect(x, y, w, h int32, stroke, fill color, thickness int32) {
+	fmt.Fprintf(&c.body, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s" stroke="%s" stroke-width="%d"/>`+"\n",
+		x, y, w, h, fill.hex(), stroke.hex(), thickness)
+}
+
+func (c *canvas) circle(cx, cy, r int32, stroke color, thickness int32) {
+	fmt.Fprintf(&c.body, `<circle cx="%d" cy="%d" r="%d" fill="none" stroke="%s" stroke-width="%d"/>`+"\n",
+		cx, cy, r, stroke.hex(), thickness)
+}
+
+func (c *canvas) line(x1, y1, x2, y2 int32, stroke color, thickness int32) {
+	fmt.Fprintf(&c.body, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="%d"/>`+"\n",
+		x1, y1, x2, y2, stroke.hex(), thickness)
+}
+
+func (c *canvas) path(d string, stroke color, thickness int32) {
+	fmt.Fprintf(&c.body, `<path d="%s" fill="none" stroke="%s" stroke-width="%d"/>`+"\n",
+		d, stroke.hex(), thickness)
+}
+
+func (c *canvas) polygon(points []point, fill color) {
+	var coords []string
+	for _, p := range points {
+		coords = append(coords, fmt.Sprintf("%d,%d", p.X, p.Y))
+	}
+
+	fmt.Fprintf(&c.body, `<polygon points="%s" fill="%s"/>`+"\n", strings.Join(coords, " "), fill.hex())
+}
+
+// anchor pode ser "start", "middle" ou "end". O texto é centralizado
+// verticalmente em y.
+func (c *canvas) text(x, y int32, text string, size int32, anchor string, fill color) {
+	fmt.Fprintf(&c.body, `<text x="%d" y="%d" font-family="monospace" font-size="%d" text-anchor="%s" dominant-baseline="central" fill="%s">%s</text>`+"\n",
+		x, y, size, anchor, fill.hex(), html.EscapeString(text))
+}
+
+func (c *canvas) writeTo(w io.Writer) error {
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n%s</svg>\n",
+		c.width, c.height, c.width, c.height, c.body.String())
+	return err
+}
@@ -0,0 +1,393 @@
+package repl
+
+import (
+	"autosimulator/src/collections"
+	"autosimulator/src/machine"
+	"autosimulator/src/reader"
+	"autosimulator/src/utils"
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	PROMPT = "(autosim) "
+	HELP   = `Comandos:
+  n, next [k]         avança k passos (padrão 1)
+  p, prev [k]         volta k passos (padrão 1)
+  g, goto <i>         vai para o passo i
+  c, continue         avança até um breakpoint ou o final
+  r, reset            volta para o passo 0
+  s, show             mostra o passo atual
+  h, hist             mostra o historico completo
+  b, break <estado>   breakpoint ao entrar no estado
+  bt <transição>      breakpoint ao fazer a transição. Ex: bt (a, q1)
+  d, delete <bp>      remove um breakpoint
+  bl                  lista os breakpoints
+  i, input <a,b,c>    troca a entrada
+  reload              lê de novo os arquivos da maquina e da entrada
+  help                mostra esta mensagem
+  q, quit             sai
+Uma linha vazia repete o ultimo comando.`
+)
+
+type Debugger struct {
+	machinePath string
+	inputPath   string
+	mode        int
+
+	machine     machine.Machine
+	input       []string
+	computation *machine.Computation
+	index       int
+
+	stateBreaks      map[string]bool
+	transitionBreaks map[string]bool
+
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+// Cria o depurador para a maquina do arquivo. Se inputPath for vazio é
+// usada a entrada default da maquina.
+func New(machinePath, inputPath string, mode int, in io.Reader, out io.Writer) (*Debugger, error) {
+	d := &Debugger{
+		machinePath:      machinePath,
+		inputPath:        inputPath,
+		mode:             mode,
+		stateBreaks:      make(map[string]bool),
+		transitionBreaks: make(map[string]bool),
+		in:               bufio.NewScanner(in),
+		out:              out,
+	}
+
+	err := d.reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (d *Debugger) Run() error {
+	d.show()
+
+	var last string
+	for {
+		fmt.Fprint(d.out, PROMPT)
+		if !d.in.Scan() {
+			return d.in.Err()
+		}
+
+		line := strings.TrimSpace(d.in.Text())
+		if line == "" {
+			line = last
+		}
+
+		last = line
+		if line == "" {
+			continue
+		}
+
+		quit, err := d.exec(line)
+		if err != nil {
+			fmt.Fprintf(d.out, "erro: %s\n", err)
+		}
+
+		if quit {
+			return nil
+		}
+	}
+}
+
+func (d *Debugger) exec(line string) (bool, error) {
+	command, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch command {
+	case "n", "next":
+		amount, err := amountArg(arg)
+		if err != nil {
+			return false, err
+		}
+
+		d.goTo(d.index + amount)
+
+	case "p", "prev":
+		amount, err := amountArg(arg)
+		if err != nil {
+			return false, err
+		}
+
+		d.goTo(d.index - amount)
+
+	case "g", "goto":
+		i, err := strconv.Atoi(arg)
+		if err != nil {
+			return false, fmt.Errorf("passo invalido: %s", arg)
+		}
+
+		d.goTo(i)
+
+	case "c", "continue":
+		d.continueRun()
+
+	case "r", "reset":
+		d.goTo(0)
+
+	case "s", "show":
+		d.show()
+
+	case "h", "hist":
+		fmt.Fprint(d.out, d.computation.Stringfy())
+
+	case "b", "break":
+		if arg == "" {
+			return false, fmt.Errorf("informe o estado")
+		}
+
+		if !utils.Contains(d.machine.GetStates(), arg) {
+			return false, fmt.Errorf("estado não existe: %s", arg)
+		}
+
+		d.stateBreaks[arg] = true
+		fmt.Fprintf(d.out, "breakpoint no estado %s\n", arg)
+
+	case "bt":
+		if arg == "" {
+			return false, fmt.Errorf("informe a transição")
+		}
+
+		if !d.hasTransition(arg) {
+			return false, fmt.Errorf("transição não existe: %s", arg)
+		}
+
+		d.transitionBreaks[normalize(arg)] = true
+		fmt.Fprintf(d.out, "breakpoint na transição %s\n", arg)
+
+	case "d", "delete":
+		if d.stateBreaks[arg] {
+			delete(d.stateBreaks, arg)
+		} else if d.transitionBreaks[normalize(arg)] {
+			delete(d.transitionBreaks, normalize(arg))
+		} else {
+			return false, fmt.Errorf("breakpoint não existe: %s", arg)
+		}
+
+	case "bl":
+		d.listBreakpoints()
+
+	case "i", "input":
+		previusInput, previusPath := d.input, d.inputPath
+		d.input = splitInput(arg)
+		d.inputPath = ""
+		err := d.execute()
+		if err != nil {
+			d.input, d.inputPath = previusInput, previusPath
+			return false, err
+		}
+
+		d.goTo(0)
+
+	case "reload":
+		err := d.reload()
+		if err != nil {
+			return false, err
+		}
+
+		d.goTo(d.index)
+
+	case "help":
+		fmt.Fprintln(d.out, HELP)
+
+	case "q", "quit", "exit":
+		return true, nil
+
+	default:
+		return false, fmt.Errorf("comando desconhecido: %s (digite help)", command)
+	}
+
+	return false, nil
+}
+
+// Lê de novo a maquina e a entrada e roda a computação
+func (d *Debugger) reload() error {
+	m, err := reader.ReadMachine(d.machinePath)
+	if err != nil {
+		return err
+	}
+
+	input := d.input
+	if d.inputPath != "" {
+		fita, err := reader.ReadInput(d.inputPath)
+		if err != nil {
+			return err
+		}
+
+		input = withoutTail(fita.ToArray())
+	} else if input == nil {
+		input = withoutTail(m.GetInput().ToArray())
+	}
+
+	previusMachine, previusInput := d.machine, d.input
+	d.machine = m
+	d.input = input
+	err = d.execute()
+	if err != nil {
+		d.machine, d.input = previusMachine, previusInput
+		return err
+	}
+
+	fmt.Fprintf(d.out, "maquina carregada: %s\n", d.machinePath)
+	return nil
+}
+
+func (d *Debugger) execute() error {
+	// O historico é mostrado pelos comandos, não na execução
+	comp, err := machine.ExecuteWithOptions(d.machine, collections.FitaFromArray(d.input), machine.ExecOptions{
+		Mode:     d.mode,
+		MaxSteps: machine.MAX_STEPS,
+		Quiet:    true,
+	})
+	if err != nil {
+		return err
+	}
+
+	d.computation = comp
+	for _, v := range comp.InvalidSymbols {
+		fmt.Fprintf(d.out, "aviso: %s\n", v.Stringfy())
+	}
+
+	if comp.Limited {
+		fmt.Fprintf(d.out, "aviso: a computação parou no limite de %d passos\n", machine.MAX_STEPS)
+	}
+
+	return nil
+}
+
+func (d *Debugger) goTo(i int) {
+	last := len(d.computation.History) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	if i > last {
+		i = last
+	}
+
+	d.index = i
+	d.show()
+}
+
+// Avança até entrar em um estado ou fazer uma transição com breakpoint
+func (d *Debugger) continueRun() {
+	last := len(d.computation.History) - 1
+	for i := d.index + 1; i <= last; i++ {
+		details := d.computation.History[i].Details()
+		if d.stateBreaks[details["NEXT_STATE"]] {
+			fmt.Fprintf(d.out, "breakpoint: estado %s\n", details["NEXT_STATE"])
+			d.goTo(i)
+			return
+		}
+
+		if d.transitionBreaks[normalize(details["TRANSITION"])] {
+			fmt.Fprintf(d.out, "breakpoint: transição %s\n", details["TRANSITION"])
+			d.goTo(i)
+			return
+		}
+	}
+
+	d.goTo(last)
+}
+
+func (d *Debugger) show() {
+	record := d.computation.History[d.index]
+	details := record.Details()
+
+	fmt.Fprintf(d.out, "passo %d/%d: %s\n", d.index, len(d.computation.History)-1, record.Stringfy())
+	if details["TRANSITION"] != "" {
+		fmt.Fprintf(d.out, "  transição: %s\n", details["TRANSITION"])
+	}
+
+	// Cada passo consome um simbolo da fita
+	fita := collections.FitaFromArray(d.input)
+	head := d.index
+	if head > fita.Length()-1 {
+		head = fita.Length() - 1
+	}
+
+	fmt.Fprintf(d.out, "  fita:  %s\n", fita.StringfyHead(head))
+	for i, stack := range record.Stacks() {
+		fmt.Fprintf(d.out, "  pilha %d (topo primeiro): %s\n", i+1, collections.StackFromArray(stack).Stringfy())
+	}
+}
+
+func (d *Debugger) listBreakpoints() {
+	var states, transitions []string
+	for state := range d.stateBreaks {
+		states = append(states, state)
+	}
+
+	for transition := range d.transitionBreaks {
+		transitions = append(transitions, transition)
+	}
+
+	sort.Strings(states)
+	sort.Strings(transitions)
+	fmt.Fprintf(d.out, "estados: %v\ntransições: %v\n", states, transitions)
+}
+
+func amountArg(arg string) (int, error) {
+	if arg == "" {
+		return 1, nil
+	}
+
+	amount, err := strconv.Atoi(arg)
+	if err != nil || amount < 0 {
+		return 0, fmt.Errorf("quantidade invalida: %s", arg)
+	}
+
+	return amount, nil
+}
+
+// Remove os espaços para comparar transições digitadas com as da maquina
+// Se alguma transição da maquina, de qualquer estado, é transition
+func (d *Debugger) hasTransition(transition string) bool {
+	for _, state := range d.machine.GetStates() {
+		for _, t := range d.machine.GetTransitions(state) {
+			if normalize(t.Stringfy()) == normalize(transition) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func normalize(transition string) string {
+	return strings.ReplaceAll(transition, " ", "")
+}
+
+func splitInput(arg string) []string {
+	if arg == "" {
+		return []string{}
+	}
+
+	symbols := strings.Split(arg, ",")
+	for i := range symbols {
+		symbols[i] = strings.TrimSpace(symbols[i])
+	}
+
+	return symbols
+}
+
+func withoutTail(symbols []string) []string {
+	if len(symbols) > 0 && symbols[len(symbols)-1] == collections.TAIL_FITA {
+		return symbols[:len(symbols)-1]
+	}
+
+	return symbols
+}
@@ -0,0 +1,393 @@
+package server
+
+import (
+	"autosimulator/src/collections"
+	"autosimulator/src/machine"
+	"autosimulator/src/reader"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	// Tamanho maximo aceito no corpo das requisições
+	MAX_BODY = 1 << 20
+
+	// Computações guardadas para GET /computations. As mais antigas são
+	// descartadas.
+	MAX_COMPUTATIONS = 1000
+)
+
+type (
+	// Servidor HTTP que expõe o simulador. As maquinas são guardadas como o
+	// JSON original, pois as maquinas carregadas guardam o estado da execução
+	// e precisam ser lidas de novo a cada computação.
+	Server struct {
+		mu           sync.Mutex
+		machines     map[string][]byte
+		computations map[string]*machine.Computation
+		nextId       int
+
+		// Ids das computações guardadas, da mais antiga para a mais nova
+		computationIds []string
+
+		// Aceita o /ws vindo de paginas de outras origens. Desligado, só a
+		// origem do proprio servidor e clientes sem Origin conectam.
+		CrossOrigin bool
+	}
+
+	runRequest struct {
+		MachineId string          `json:"machineId"`
+		Machine   json.RawMessage `json:"machine"`
+		Inputs    [][]string      `json:"inputs"`
+		Strict    bool            `json:"strict"`
+	}
+
+	runResult struct {
+		Id             string                  `json:"id,omitempty"`
+		Input          []string                `json:"input"`
+		Accepted       bool                    `json:"accepted"`
+		Limited        bool                    `json:"limited,omitempty"`
+		Steps          int                     `json:"steps"`
+		InvalidSymbols []machine.InvalidSymbol `json:"invalidSymbols,omitempty"`
+		Error          string                  `json:"error,omitempty"`
+	}
+
+	machineInfo struct {
+		Id           string   `json:"id,omitempty"`
+		Valid        bool     `json:"valid"`
+		Type         int      `json:"type"`
+		States       []string `json:"states,omitempty"`
+		InitialState string   `json:"initialState,omitempty"`
+		FinalStates  []string `json:"finalStates,omitempty"`
+		Alfabet      []string `json:"alfabet,omitempty"`
+		Error        string   `json:"error,omitempty"`
+	}
+)
+
+func New() *Server {
+	return &Server{
+		machines:     make(map[string][]byte),
+		computations: make(map[string]*machine.Computation),
+	}
+}
+
+// Carrega as maquinas de um diretorio. O id de cada maquina é o nome do
+// arquivo sem a extensão.
+func (s *Server) LoadMachines(path string) error {
+	files, err := reader.GetJsonList(path)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		content, err := os.ReadFile(filepath.Join(path, file))
+		if err != nil {
+			return err
+		}
+
+		_, err = reader.ParseMachine(file, content)
+		if err != nil {
+			fmt.Printf("maquina ignorada %s: %s\n", file, err)
+			continue
+		}
+
+		s.machines[strings.TrimSuffix(file, filepath.Ext(file))] = content
+	}
+
+	return nil
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/machines", s.handleMachines)
+	mux.HandleFunc("/machines/", s.handleMachine)
+	mux.HandleFunc("/run", s.handleRun)
+	mux.HandleFunc("/computations/", s.handleComputation)
+	mux.HandleFunc("/ws", s.handleStream)
+	return mux
+}
+
+// GET lista as maquinas, POST envia uma nova maquina
+func (s *Server) handleMachines(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		ids := make([]string, 0, len(s.machines))
+		for id := range s.machines {
+			ids = append(ids, id)
+		}
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, ids)
+
+	case http.MethodPost:
+		content, err := readBody(w, r)
+		if err != nil {
+			writeBodyError(w, err)
+			return
+		}
+
+		info := validate(content)
+		if !info.Valid {
+			writeJSON(w, http.StatusUnprocessableEntity, info)
+			return
+		}
+
+		s.mu.Lock()
+		info.Id = s.newId("m")
+		s.machines[info.Id] = content
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusCreated, info)
+
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+// GET /machines/<id> retorna a definição, POST /machines/validate valida
+// uma definição sem guardar
+func (s *Server) handleMachine(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/machines/")
+	if id == "validate" {
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w)
+			return
+		}
+
+		content, err := readBody(w, r)
+		if err != nil {
+			writeBodyError(w, err)
+			return
+		}
+
+		info := validate(content)
+		status := http.StatusOK
+		if !info.Valid {
+			status = http.StatusUnprocessableEntity
+		}
+
+		writeJSON(w, status, info)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+
+	s.mu.Lock()
+	content, ok := s.machines[id]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("maquina não encontrada: %s", id))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(content)
+}
+
+// Roda a maquina em uma ou mais entradas. Se não houver entradas é usada a
+// entrada default da maquina.
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w)
+		return
+	}
+
+	body, err := readBody(w, r)
+	if err != nil {
+		writeBodyError(w, err)
+		return
+	}
+
+	var req runRequest
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	content, err := s.machineContent(req)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	m, err := reader.ParseMachine("machine", content)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	var inputs []*collections.Fita
+	for _, input := range req.Inputs {
+		inputs = append(inputs, collections.FitaFromArray(input))
+	}
+
+	if len(inputs) == 0 {
+		inputs = append(inputs, m.GetInput())
+	}
+
+	mode := machine.LENIENT_ALFABET
+	if req.Strict {
+		mode = machine.STRICT_ALFABET
+	}
+
+	results := make([]runResult, 0, len(inputs))
+	for _, input := range inputs {
+		results = append(results, s.run(content, input, mode))
+	}
+
+	writeJSON(w, http.StatusOK, map[string][]runResult{"results": results})
+}
+
+// GET /computations/<id> retorna o historico completo da computação
+func (s *Server) handleComputation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/computations/")
+	s.mu.Lock()
+	comp, ok := s.computations[id]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("computação não encontrada: %s", id))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, comp)
+}
+
+func (s *Server) run(content []byte, input *collections.Fita, mode int) runResult {
+	symbols := input.ToArray()
+	result := runResult{Input: symbols[:len(symbols)-1]}
+
+	// Cada execução precisa de uma maquina nova
+	m, err := reader.ParseMachine("machine", content)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	comp, err := execute(m, input, mode)
+	if err != nil {
+		result.Error = err.Error()
+		if alfabetErr, ok := err.(*machine.AlfabetError); ok {
+			result.InvalidSymbols = alfabetErr.Symbols
+		}
+
+		return result
+	}
+
+	s.mu.Lock()
+	result.Id = s.newId("c")
+	s.storeComputation(result.Id, comp)
+	s.mu.Unlock()
+
+	result.Accepted = comp.Accepted()
+	result.Limited = comp.Limited
+	result.Steps = len(comp.History) - 1
+	result.InvalidSymbols = comp.InvalidSymbols
+	return result
+}
+
+func (s *Server) machineContent(req runRequest) ([]byte, error) {
+	if len(req.Machine) > 0 {
+		return req.Machine, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, ok := s.machines[req.MachineId]
+	if !ok {
+		return nil, fmt.Errorf("maquina não encontrada: %s", req.MachineId)
+	}
+
+	return content, nil
+}
+
+// Executa sem imprimir o historico, que lotaria o log do servidor, e com o
+// limite de passos: uma maquina enviada pelo cliente pode nunca parar
+func execute(m machine.Machine, input *collections.Fita, mode int) (*machine.Computation, error) {
+	return machine.ExecuteWithOptions(m, input, machine.ExecOptions{
+		Mode:     mode,
+		MaxSteps: machine.MAX_STEPS,
+		Quiet:    true,
+	})
+}
+
+// Deve ser chamado com o mutex travado
+func (s *Server) storeComputation(id string, comp *machine.Computation) {
+	if len(s.computationIds) >= MAX_COMPUTATIONS {
+		delete(s.computations, s.computationIds[0])
+		s.computationIds = s.computationIds[1:]
+	}
+
+	s.computations[id] = comp
+	s.computationIds = append(s.computationIds, id)
+}
+
+// Deve ser chamado com o mutex travado
+func (s *Server) newId(prefix string) string {
+	s.nextId++
+	return fmt.Sprintf("%s%d", prefix, s.nextId)
+}
+
+func validate(content []byte) machineInfo {
+	m, err := reader.ParseMachine("machine", content)
+	if err != nil {
+		return machineInfo{Valid: false, Error: err.Error()}
+	}
+
+	return machineInfo{
+		Valid:        true,
+		Type:         m.Type(),
+		States:       m.GetStates(),
+		InitialState: m.GetInitialState(),
+		FinalStates:  m.GetFinalStates(),
+		Alfabet:      m.GetAlfabet(),
+	}
+}
+
+// Lê o corpo da requisição. Corpos maiores que MAX_BODY retornam um
+// *http.MaxBytesError.
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	defer r.Body.Close()
+	return io.ReadAll(http.MaxBytesReader(w, r.Body, MAX_BODY))
+}
+
+// 413 para corpos grandes demais e 400 para os outros erros de leitura
+func writeBodyError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("o corpo da requisição passa de %d bytes", MAX_BODY))
+		return
+	}
+
+	writeError(w, http.StatusBadRequest, err)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func methodNotAllowed(w http.ResponseWriter) {
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("método não permitido"))
+}
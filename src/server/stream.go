@@ -0,0 +1,265 @@
+package server
+
+import (
+	"autosimulator/src/collections"
+	"autosimulator/src/machine"
+	"autosimulator/src/reader"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// Mesmo delay inicial e passo da animação da interface grafica
+	DELAY_DEFAULT = 0.5
+	DELAY_STEP    = 0.1
+)
+
+type (
+	// Mensagens enviadas pelo cliente. "run" carrega uma maquina e uma
+	// entrada; os outros comandos espelham as teclas da interface grafica:
+	// "toggle" (espaço), "step" (seta para baixo), "slower" (=), "faster" (-)
+	// e "reset" (r).
+	streamCommand struct {
+		Type      string          `json:"type"`
+		MachineId string          `json:"machineId"`
+		Machine   json.RawMessage `json:"machine"`
+		Input     []string        `json:"input"`
+		Strict    bool            `json:"strict"`
+		Paused    bool            `json:"paused"`
+		Delay     *float64        `json:"delay"`
+	}
+
+	streamMessage struct {
+		Type     string                     `json:"type"`
+		Index    int                        `json:"index"`
+		Total    int                        `json:"total,omitempty"`
+		Record   *machine.ComputationRecord `json:"record,omitempty"`
+		Accepted *bool                      `json:"accepted,omitempty"`
+		Limited  bool                       `json:"limited,omitempty"`
+		Running  bool                       `json:"running"`
+		Delay    float64                    `json:"delay,omitempty"`
+		Error    string                     `json:"error,omitempty"`
+	}
+
+	// Estado da animação de uma conexão
+	stream struct {
+		conn        *wsConn
+		computation *machine.Computation
+		index       int
+		running     bool
+		delay       float64
+	}
+)
+
+// Cada conexão recebe uma mensagem por transição enquanto a maquina roda
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	// Sem isso qualquer pagina aberta no navegador poderia usar o servidor
+	// local
+	if !s.CrossOrigin && !sameOrigin(r) {
+		writeError(w, http.StatusForbidden, fmt.Errorf("origem não permitida: %s", r.Header.Get("Origin")))
+		return
+	}
+
+	conn, err := upgrade(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	defer conn.Close()
+
+	commands := make(chan streamCommand)
+	done := make(chan struct{})
+	defer close(done)
+	go readCommands(conn, commands, done)
+
+	st := &stream{conn: conn, delay: DELAY_DEFAULT}
+	for {
+		var tick <-chan time.Time
+		if st.running {
+			tick = time.After(time.Duration(st.delay * float64(time.Second)))
+		}
+
+		select {
+		case cmd, ok := <-commands:
+			if !ok {
+				return
+			}
+
+			err = s.handleCommand(st, cmd)
+
+		case <-tick:
+			err = st.next()
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+func readCommands(conn *wsConn, commands chan<- streamCommand, done <-chan struct{}) {
+	defer close(commands)
+	for {
+		data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var cmd streamCommand
+		err = json.Unmarshal(data, &cmd)
+		if err != nil {
+			cmd = streamCommand{Type: "invalid"}
+		}
+
+		select {
+		case commands <- cmd:
+		case <-done:
+			return
+		}
+	}
+}
+
+func (s *Server) handleCommand(st *stream, cmd streamCommand) error {
+	if cmd.Delay != nil {
+		st.setDelay(*cmd.Delay)
+	}
+
+	switch cmd.Type {
+	case "run":
+		comp, err := s.streamComputation(cmd)
+		if err != nil {
+			return st.send(streamMessage{Type: "error", Error: err.Error()})
+		}
+
+		st.computation = comp
+		st.index = 0
+		st.running = !cmd.Paused
+		return st.sendRecord()
+
+	case "pause":
+		st.running = false
+
+	case "resume":
+		st.running = st.computation != nil && !st.finished()
+
+	case "toggle":
+		st.running = !st.running && st.computation != nil && !st.finished()
+
+	case "step":
+		st.running = false
+		return st.next()
+
+	case "slower":
+		st.setDelay(st.delay + DELAY_STEP)
+
+	case "faster":
+		st.setDelay(st.delay - DELAY_STEP)
+
+	case "delay":
+		// O delay já foi aplicado acima
+
+	case "reset":
+		if st.computation != nil {
+			st.index = 0
+			st.running = false
+			return st.sendRecord()
+		}
+
+	default:
+		return st.send(streamMessage{Type: "error", Error: fmt.Sprintf("comando invalido: %s", cmd.Type)})
+	}
+
+	return st.sendStatus()
+}
+
+func (s *Server) streamComputation(cmd streamCommand) (*machine.Computation, error) {
+	content, err := s.machineContent(runRequest{MachineId: cmd.MachineId, Machine: cmd.Machine})
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := reader.ParseMachine("machine", content)
+	if err != nil {
+		return nil, err
+	}
+
+	input := m.GetInput()
+	if cmd.Input != nil {
+		input = collections.FitaFromArray(cmd.Input)
+	}
+
+	mode := machine.LENIENT_ALFABET
+	if cmd.Strict {
+		mode = machine.STRICT_ALFABET
+	}
+
+	return execute(m, input, mode)
+}
+
+// Envia o proximo passo da computação. Ao chegar no ultimo passo envia o
+// resultado e para a animação.
+func (st *stream) next() error {
+	if st.computation == nil || st.finished() {
+		st.running = false
+		return nil
+	}
+
+	st.index++
+	return st.sendRecord()
+}
+
+func (st *stream) sendRecord() error {
+	err := st.send(streamMessage{
+		Type:    "step",
+		Index:   st.index,
+		Total:   len(st.computation.History),
+		Record:  &st.computation.History[st.index],
+		Running: st.running,
+		Delay:   st.delay,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !st.finished() {
+		return nil
+	}
+
+	st.running = false
+	accepted := st.computation.Accepted()
+	return st.send(streamMessage{
+		Type:     "done",
+		Index:    st.index,
+		Total:    len(st.computation.History),
+		Accepted: &accepted,
+		Limited:  st.computation.Limited,
+	})
+}
+
+func (st *stream) sendStatus() error {
+	return st.send(streamMessage{Type: "status", Index: st.index, Running: st.running, Delay: st.delay})
+}
+
+func (st *stream) send(msg streamMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	return st.conn.WriteMessage(data)
+}
+
+func (st *stream) finished() bool {
+	return st.index >= len(st.computation.History)-1
+}
+
+func (st *stream) setDelay(delay float64) {
+	if delay < 0 {
+		delay = 0
+	}
+
+	st.delay = delay
+}
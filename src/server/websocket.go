@@ -0,0 +1,248 @@
+package server
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Implementação minima do protocolo WebSocket (RFC 6455), suficiente para
+// trocar mensagens de texto com um navegador.
+
+const (
+	WS_GUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	OP_CONTINUATION = 0x0
+	OP_TEXT         = 0x1
+	OP_BINARY       = 0x2
+	OP_CLOSE        = 0x8
+	OP_PING         = 0x9
+	OP_PONG         = 0xA
+)
+
+type wsConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+
+	// Escritas podem vir da goroutine de leitura (pong/close). closed é
+	// escrito pela goroutine de leitura e lido em Close, também sob writeMu.
+	writeMu sync.Mutex
+	closed  bool
+}
+
+var errWsClosed = errors.New("conexão websocket fechada")
+
+func upgrade(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		return nil, errors.New("a requisição não é um upgrade para websocket")
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, errors.New("Sec-WebSocket-Key ausente")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("o servidor não suporta hijack da conexão")
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha1.Sum([]byte(key + WS_GUID))
+	accept := base64.StdEncoding.EncodeToString(hash[:])
+
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	rw.WriteString("Upgrade: websocket\r\n")
+	rw.WriteString("Connection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + accept + "\r\n\r\n")
+	err = rw.Flush()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &wsConn{conn: conn, rw: rw}, nil
+}
+
+// Se o Origin da requisição é o proprio servidor. Clientes que não são
+// navegadores não mandam Origin e são aceitos.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// Lê a proxima mensagem de texto. Pings são respondidos e frames
+// fragmentados são juntados.
+func (c *wsConn) ReadMessage() ([]byte, error) {
+	var message []byte
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case OP_PING:
+			err = c.writeFrame(OP_PONG, payload)
+			if err != nil {
+				return nil, err
+			}
+			continue
+
+		case OP_PONG:
+			continue
+
+		case OP_CLOSE:
+			c.writeMu.Lock()
+			c.writeFrameLocked(OP_CLOSE, payload)
+			c.closed = true
+			c.writeMu.Unlock()
+			return nil, errWsClosed
+		}
+
+		// Cada frame respeita o limite, mas a mensagem fragmentada também
+		if len(message)+len(payload) > MAX_BODY {
+			return nil, errors.New("mensagem websocket muito grande")
+		}
+
+		message = append(message, payload...)
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+func (c *wsConn) WriteMessage(data []byte) error {
+	return c.writeFrame(OP_TEXT, data)
+}
+
+func (c *wsConn) Close() error {
+	c.writeMu.Lock()
+	if !c.closed {
+		c.writeFrameLocked(OP_CLOSE, nil)
+		c.closed = true
+	}
+	c.writeMu.Unlock()
+
+	return c.conn.Close()
+}
+
+func (c *wsConn) readFrame() (bool, byte, []byte, error) {
+	header := make([]byte, 2)
+	_, err := io.ReadFull(c.rw, header)
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	fin := header[0]&0x80 != 0
+	opcode := header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7F)
+
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err = io.ReadFull(c.rw, ext); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext))
+
+	case 127:
+		ext := make([]byte, 8)
+		if _, err = io.ReadFull(c.rw, ext); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext)
+	}
+
+	if length > MAX_BODY {
+		return false, 0, nil, errors.New("mensagem websocket muito grande")
+	}
+
+	// Mensagens do cliente sempre vem com mascara
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.rw, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	payload := make([]byte, length)
+	if _, err = io.ReadFull(c.rw, payload); err != nil {
+		return false, 0, nil, err
+	}
+
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+
+	return fin, opcode, payload, nil
+}
+
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.writeFrameLocked(opcode, payload)
+}
+
+// Deve ser chamado com writeMu travado
+func (c *wsConn) writeFrameLocked(opcode byte, payload []byte) error {
+	// Mensagens do servidor não usam mascara
+	header := []byte{0x80 | opcode}
+	length := len(payload)
+	switch {
+	case length < 126:
+		header = append(header, byte(length))
+	case length <= 0xFFFF:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(length))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+	}
+
+	if _, err := c.rw.Write(header); err != nil {
+		return err
+	}
+
+	if _, err := c.rw.Write(payload); err != nil {
+		return err
+	}
+
+	return c.rw.Flush()
+}
+
+func headerContains(header http.Header, name, value string) bool {
+	for _, v := range header.Values(name) {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), value) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
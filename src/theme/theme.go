@@ -0,0 +1,171 @@
+package theme
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Temas de cores, compartilhados pela interface grafica e pelo
+// renderizador em SVG. Além dos temas embutidos, o tema pode ser um arquivo
+// JSON com as cores em hexadecimal ("#rrggbb"); as cores que faltam vêm do
+// tema escuro.
+
+const (
+	THEME_DARK       = "dark"
+	THEME_LIGHT      = "light"
+	THEME_COLORBLIND = "colorblind"
+)
+
+type (
+	// Cor opaca, sem depender do SDL
+	Color struct {
+		R, G, B uint8
+	}
+
+	Theme struct {
+		Background string `json:"background"`
+		Default    string `json:"default"`
+
+		// Estado atual no passo inicial, durante a computação, ao aceitar e
+		// ao rejeitar
+		Initial  string `json:"initial"`
+		Current  string `json:"current"`
+		Accepted string `json:"accepted"`
+		Rejected string `json:"rejected"`
+
+		// Ramos mortos da arvore e entradas fora do alfabeto
+		Warning string `json:"warning"`
+
+		// Paineis de erro, divergencias e breakpoints
+		Error string `json:"error"`
+
+		// Celulas já lidas da fita
+		Consumed string `json:"consumed"`
+
+		// Seleções e marcadores sobre as outras cores
+		Highlight string `json:"highlight"`
+	}
+)
+
+// Ordem da tecla k
+var THEME_NAMES = []string{THEME_DARK, THEME_LIGHT, THEME_COLORBLIND}
+
+var themes = map[string]Theme{
+	// As cores originais do simulador
+	THEME_DARK: {
+		Background: "#121212",
+		Default:    "#ebae34",
+		Initial:    "#0000ff",
+		Current:    "#ff0000",
+		Accepted:   "#00ff00",
+		Rejected:   "#ff0000",
+		Warning:    "#ff5050",
+		Error:      "#ff0000",
+		Consumed:   "#6e6e6e",
+		Highlight:  "#ffffff",
+	},
+
+	// Alto contraste em fundo branco, para projetores
+	THEME_LIGHT: {
+		Background: "#ffffff",
+		Default:    "#000000",
+		Initial:    "#0033cc",
+		Current:    "#b35900",
+		Accepted:   "#007a33",
+		Rejected:   "#c00000",
+		Warning:    "#a0006e",
+		Error:      "#c00000",
+		Consumed:   "#8c8c8c",
+		Highlight:  "#0077ff",
+	},
+
+	// Paleta de Okabe e Ito, distinguivel por daltonicos. Aceita e rejeita
+	// usam azul e vermelhão em vez de verde e vermelho.
+	THEME_COLORBLIND: {
+		Background: "#121212",
+		Default:    "#e0e0e0",
+		Initial:    "#56b4e9",
+		Current:    "#e69f00",
+		Accepted:   "#0072b2",
+		Rejected:   "#d55e00",
+		Warning:    "#cc79a7",
+		Error:      "#d55e00",
+		Consumed:   "#707070",
+		Highlight:  "#f0e442",
+	},
+}
+
+// Se name é um tema embutido
+func IsBuiltin(name string) bool {
+	_, ok := themes[name]
+	return ok
+}
+
+// Tema embutido com o nome name ou lido do arquivo JSON name. Vazio é o
+// tema escuro.
+func Load(name string) (Theme, error) {
+	if name == "" {
+		name = THEME_DARK
+	}
+
+	if theme, ok := themes[name]; ok {
+		return theme, nil
+	}
+
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return Theme{}, fmt.Errorf("tema não encontrado: %s. Temas embutidos: %s", name, strings.Join(THEME_NAMES, ", "))
+	}
+
+	theme := themes[THEME_DARK]
+	err = json.Unmarshal(content, &theme)
+	if err != nil {
+		return Theme{}, fmt.Errorf("erro ao tentar fazer o unmarshal do arquivo %s. err: %s", name, err)
+	}
+
+	// Confere todas as cores antes de aplicar
+	_, err = theme.Colors()
+	if err != nil {
+		return Theme{}, fmt.Errorf("tema %s: %s", name, err)
+	}
+
+	return theme, nil
+}
+
+// Cores na ordem dos campos
+func (theme Theme) Colors() ([]Color, error) {
+	hexes := []string{
+		theme.Background, theme.Default, theme.Initial, theme.Current, theme.Accepted,
+		theme.Rejected, theme.Warning, theme.Error, theme.Consumed, theme.Highlight,
+	}
+
+	colors := make([]Color, len(hexes))
+	for i, hex := range hexes {
+		color, err := parseColor(hex)
+		if err != nil {
+			return nil, err
+		}
+
+		colors[i] = color
+	}
+
+	return colors, nil
+}
+
+// Converte "#rrggbb" em uma cor
+func parseColor(hex string) (Color, error) {
+	digits := strings.TrimPrefix(hex, "#")
+	if len(digits) != 6 {
+		return Color{}, fmt.Errorf("cor invalida: %q. Use o formato #rrggbb", hex)
+	}
+
+	value, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil {
+		return Color{}, fmt.Errorf("cor invalida: %q. Use o formato #rrggbb", hex)
+	}
+
+	return Color{R: uint8(value >> 16), G: uint8(value >> 8), B: uint8(value)}, nil
+}
@@ -1,16 +1,21 @@
 package utils
 
 import (
-	"errors"
 	"fmt"
 )
 
-func ParseTransition(s string) ([]string, error) {
+// Lê a transição em JSON, ex: "(a, q1)", e confere se ela tem arity
+// elementos. Transições mal formadas retornam erro.
+func ParseTransition(s string, arity int) ([]string, error) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return []string{}, fmt.Errorf("transição deve ser uma string: %s", s)
+	}
+
 	// retira os double quotes do json
 	s = s[1 : len(s)-1]
 
-	if s[0] != '(' || s[len(s)-1] != ')' {
-		return []string{}, errors.New("transicao deve começar com '(' e terminar com ')'")
+	if len(s) < 3 || s[0] != '(' || s[len(s)-1] != ')' {
+		return []string{}, fmt.Errorf("transicao deve começar com '(' e terminar com ')': %q", s)
 	}
 
 	i, j := 2, 1
@@ -24,6 +29,10 @@ func ParseTransition(s string) ([]string, error) {
 		switch currentChar := s[i]; currentChar {
 		case ')':
 			result = appendWithVoidWord(result, s[j:i])
+			if len(result) != arity {
+				return []string{}, fmt.Errorf("transição %s deve ter %d elementos, tem %d", s, arity, len(result))
+			}
+
 			return result, nil
 		case ',':
 			result = appendWithVoidWord(result, s[j:i])